@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/blrsn/zentile/ipc"
+	"github.com/blrsn/zentile/state"
+)
+
+// action is a command that can be triggered by a keybinding or over IPC.
+// It operates on the given workspace.
+type action func(t *tracker, ws *Workspace)
+
+// actions maps the names used in the keybindings config to their actions.
+var actions = map[string]action{
+	"tile": func(t *tracker, ws *Workspace) {
+		ws.IsTiling = true
+		ws.Tile()
+		ws.publish("tile")
+	},
+	"untile": func(t *tracker, ws *Workspace) {
+		ws.Untile()
+	},
+	"make_active_window_master": func(t *tracker, ws *Workspace) {
+		c, ok := t.clients[state.ActiveWin]
+		if !ok {
+			return
+		}
+		ws.ActiveLayout().MakeMaster(*c)
+		ws.Tile()
+		ws.publish("masters")
+	},
+	"switch_layout": func(t *tracker, ws *Workspace) {
+		ws.SwitchLayout()
+	},
+	"increase_master": func(t *tracker, ws *Workspace) {
+		ws.ActiveLayout().IncMaster()
+		ws.Tile()
+		ws.publish("masters")
+	},
+	"decrease_master": func(t *tracker, ws *Workspace) {
+		ws.ActiveLayout().DecreaseMaster()
+		ws.Tile()
+		ws.publish("masters")
+	},
+	"next_window": func(t *tracker, ws *Workspace) {
+		ws.ActiveLayout().NextClient()
+	},
+	"previous_window": func(t *tracker, ws *Workspace) {
+		ws.ActiveLayout().PreviousClient()
+	},
+	"increment_master": func(t *tracker, ws *Workspace) {
+		ws.ActiveLayout().IncrementMaster()
+		ws.Tile()
+		ws.publish("masters")
+	},
+	"decrement_master": func(t *tracker, ws *Workspace) {
+		ws.ActiveLayout().DecrementMaster()
+		ws.Tile()
+		ws.publish("masters")
+	},
+	"toggle_float": func(t *tracker, ws *Workspace) {
+		if c, ok := t.clients[state.ActiveWin]; ok {
+			t.toggleFloat(c)
+		}
+	},
+	"quit": func(t *tracker, ws *Workspace) {
+		notify(quit)
+	},
+	"restart": func(t *tracker, ws *Workspace) {
+		notify(restart)
+	},
+	"reload_config": func(t *tracker, ws *Workspace) {
+		notify(reload)
+	},
+}
+
+// notify notifies the event loop, once until it handles the notification.
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// query returns information about the state of zentile.
+type query func(t *tracker, args []string) (interface{}, error)
+
+// queries maps the names of the IPC queries to their handlers.
+var queries = map[string]query{
+	"get_actions": func(t *tracker, args []string) (interface{}, error) {
+		names := make([]string, 0, len(actions))
+		for name := range actions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names, nil
+	},
+	"get_workspaces": func(t *tracker, args []string) (interface{}, error) {
+		type workspace struct {
+			Num      uint `json:"num"`
+			IsTiling bool `json:"is_tiling"`
+			Current  bool `json:"current"`
+		}
+
+		workspaces := make([]workspace, 0, len(t.workspaces))
+		for num, ws := range t.workspaces {
+			workspaces = append(workspaces, workspace{num, ws.IsTiling, num == state.CurrentDesk})
+		}
+		sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Num < workspaces[j].Num })
+		return workspaces, nil
+	},
+	"get_tree": func(t *tracker, args []string) (interface{}, error) {
+		return t.tree(), nil
+	},
+	// preview_layout <layout> [workspace] returns where the windows would be placed by a layout.
+	"preview_layout": func(t *tracker, args []string) (interface{}, error) {
+		if len(args) == 0 {
+			return nil, errors.New("missing layout")
+		}
+
+		ws, err := t.workspaceArg(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		return ws.preview(args[0])
+	},
+}
+
+// workspaceArg returns the workspace numbered by the first argument, or the current workspace.
+func (t *tracker) workspaceArg(args []string) (*Workspace, error) {
+	desk := state.CurrentDesk
+	if len(args) > 0 {
+		n, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace %q", args[0])
+		}
+		desk = uint(n)
+	}
+
+	ws, ok := t.workspaces[desk]
+	if !ok {
+		return nil, fmt.Errorf("workspace %d does not exist", desk)
+	}
+	return ws, nil
+}
+
+// runCommand runs an action or query received over IPC.
+// The optional first argument of an action is the workspace to run it on,
+// the current workspace is used otherwise.
+func (t *tracker) runCommand(req ipc.Request) ipc.Response {
+	if q, ok := queries[req.Action]; ok {
+		data, err := q(t, req.Args)
+		if err != nil {
+			return ipc.Response{Error: err.Error()}
+		}
+		return ipc.Response{Success: true, Data: data}
+	}
+
+	a, ok := actions[req.Action]
+	if !ok {
+		return ipc.Response{Error: fmt.Sprintf("unknown action %q", req.Action)}
+	}
+
+	ws, err := t.workspaceArg(req.Args)
+	if err != nil {
+		return ipc.Response{Error: err.Error()}
+	}
+
+	a(t, ws)
+	return ipc.Response{Success: true}
+}
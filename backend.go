@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil/xrect"
+)
+
+// Backend is the window system whose windows are tiled.
+// xBackend talks to the X server, the tests use a fakeBackend that keeps the windows in memory.
+type Backend interface {
+	// Geometry returns the geometry of the window, including decorations.
+	Geometry(w xproto.Window) (xrect.Rect, error)
+	// ClientGeometry returns the geometry of the window, excluding decorations.
+	ClientGeometry(w xproto.Window) (xrect.Rect, error)
+	Desktop(w xproto.Window) (uint, error)
+	Class(w xproto.Window) (class, instance string, err error)
+	Name(w xproto.Window) string
+	Role(w xproto.Window) string
+	Types(w xproto.Window) []string
+	States(w xproto.Window) []string
+	// TransientFor returns the window that w is a dialog of, or zero.
+	TransientFor(w xproto.Window) xproto.Window
+	SizeHints(w xproto.Window) sizeHints
+	HasDecoration(w xproto.Window) bool
+
+	// MoveResize moves the window, the size excludes decorations.
+	MoveResize(w xproto.Window, x, y, width, height int) error
+	Move(w xproto.Window, x, y int)
+	Resize(w xproto.Window, width, height int)
+	SetDecoration(w xproto.Window, decorated bool)
+	Unmaximize(w xproto.Window)
+	Activate(w xproto.Window)
+	SetDesktop(w xproto.Window, desk uint)
+	// Sync waits until the requests have been handled.
+	Sync()
+
+	// Clients returns the managed windows in stacking order.
+	Clients() []xproto.Window
+	WorkArea(desk, head uint) (x, y, width, height int)
+	ActiveHead() uint
+	// HeadCount returns the number of monitors.
+	HeadCount() uint
+	// HeadForRect returns the monitor that the rectangle is on.
+	HeadForRect(r xrect.Rect) uint
+
+	// Watch calls the handler of a property when it changes on the window.
+	// The root window reports changes to the desktop.
+	Watch(w xproto.Window, handlers map[string]func())
+	// WatchGeometry calls f with the geometry of the window, excluding decorations, when it is moved or resized.
+	// The position is relative to the frame, or to the root window if the window manager reports a move.
+	WatchGeometry(w xproto.Window, f func(x, y, width, height int))
+	// WatchHeads calls f when monitors are added, removed or resized.
+	WatchHeads(f func())
+	Unwatch(w xproto.Window)
+	Root() xproto.Window
+}
+
+// backend is used for every request to the window system.
+var backend Backend
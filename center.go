@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/BurntSushi/xgbutil/xrect"
+	log "github.com/sirupsen/logrus"
+)
+
+// CenterLayout places the masters in a centered column and
+// distributes the slaves alternately into a right and a left stack.
+type CenterLayout struct {
+	*VertHorz
+}
+
+func (l *CenterLayout) Name() string {
+	return "center"
+}
+
+func (l *CenterLayout) Do() {
+	log.Info("Switching to Center layout")
+	prepareTiling(l.All())
+	applyLayout(l, l.WorkspaceNum, l.HeadNum)
+}
+
+func (l *CenterLayout) geometry(a arrangement) []xrect.Rect {
+	wx, wy, ww, wh := a.area.Pieces()
+	ssize := len(a.slaves)
+	gap := a.gap
+
+	mx := wx
+	mw := int(float64(ww) * l.Proportion)
+
+	var left, right []tile
+	switch {
+	case ssize == 0:
+		mw = ww
+	case ssize == 1:
+		right = a.slaves
+	default:
+		mx = wx + (ww-mw)/2
+		for i, t := range a.slaves {
+			if i%2 == 0 {
+				right = append(right, t)
+			} else {
+				left = append(left, t)
+			}
+		}
+	}
+
+	leftRects := column(left, wx+gap, wy+gap, mx-wx-gap, wh-2*gap, gap)
+	rightRects := column(right, mx+mw, wy+gap, wx+ww-mx-mw-gap, wh-2*gap, gap)
+
+	// The slaves alternate between the right and the left stack.
+	rects := column(a.masters, mx+gap, wy+gap, mw-2*gap, wh-2*gap, gap)
+	for i := range a.slaves {
+		if i%2 == 0 {
+			rects = append(rects, rightRects[i/2])
+		} else {
+			rects = append(rects, leftRects[i/2])
+		}
+	}
+	return rects
+}
@@ -1,78 +1,131 @@
 package main
 
 import (
-	"strings"
-
 	"github.com/BurntSushi/xgb/xproto"
-	"github.com/BurntSushi/xgbutil/ewmh"
-	"github.com/BurntSushi/xgbutil/icccm"
-	"github.com/BurntSushi/xgbutil/motif"
 	"github.com/BurntSushi/xgbutil/xrect"
-	"github.com/BurntSushi/xgbutil/xwindow"
 	"github.com/blrsn/zentile/state"
 	log "github.com/sirupsen/logrus"
 )
 
 type Client struct {
-	window    *xwindow.Window
-	Desk      uint // Desktop the client is currently in.
-	savedProp Prop // Properties that the client had, before it was tiled.
+	window          xproto.Window
+	class           string    // Class part of WM_CLASS.
+	Desk            uint      // Desktop the client is currently in, the current desktop for sticky clients.
+	sticky          bool      // Sticky clients are shown on all desktops.
+	Head            uint      // Monitor the client is currently in.
+	savedProp       Prop      // Properties that the client had, before it was tiled.
+	floating        bool      // Floating clients are tracked, but not tiled.
+	floatedBySticky bool      // Set if the client floats only because it is sticky.
+	placement       placement // Where the client is added in the layouts.
+	hints           sizeHints // Size constraints requested by the client.
+	applied         *applied  // Geometry the client was last moved to, shared by the copies of the client.
+}
+
+// applied caches the geometry and decorations that were last requested for a client,
+// so that retiling skips the clients that are already in place.
+type applied struct {
+	geom        xrect.Rect // Nil if unknown.
+	decorKnown  bool
+	decorWidth  int
+	decorHeight int
+	decorLeft   int // Position of the window in its frame.
+	decorTop    int
+	undecorated bool
+}
+
+// invalidate forgets the geometry, after the client was moved by someone else.
+func (a *applied) invalidate() {
+	a.geom = nil
+	a.decorKnown = false
 }
 
+// stickyDesk is the _NET_WM_DESKTOP of windows that are shown on all desktops.
+const stickyDesk = 0xFFFFFFFF
+
+type placement int
+
+const (
+	placeAuto   placement = iota // Master if there is room, slave otherwise.
+	placeMaster                  // Always added as a master.
+	placeStack                   // Never promoted to master automatically.
+)
+
 type Prop struct {
 	Geom       xrect.Rect
 	decoration bool
 }
 
 func newClient(w xproto.Window) (c Client) {
-	win := xwindow.New(state.X, w)
-
-	desk, err := ewmh.WmDesktopGet(state.X, w)
+	desk, err := backend.Desktop(w)
 	if err != nil {
 		desk = state.CurrentDesk
 	}
 
-	savedGeom, err := win.DecorGeometry()
+	savedGeom, err := backend.Geometry(w)
 	if err != nil {
 		log.Info(err)
 	}
 
+	class, _, _ := backend.Class(w)
+
+	sticky := desk == stickyDesk
+	if sticky {
+		desk = state.CurrentDesk
+	}
+
+	decoration := hasDecoration(w)
 	c = Client{
-		window: win,
+		window: w,
+		class:  class,
 		Desk:   desk,
+		sticky: sticky,
+		Head:   backend.HeadForRect(savedGeom),
+		hints:  backend.SizeHints(w),
+		// Sticky clients are tiled on the current desktop only if configured.
+		floating:        sticky && Config.Sticky != "tile",
+		floatedBySticky: sticky && Config.Sticky != "tile",
 		savedProp: Prop{
 			Geom:       savedGeom,
-			decoration: hasDecoration(w),
+			decoration: decoration,
 		},
+		applied: &applied{undecorated: !decoration},
 	}
 
 	return c
 }
 
 func (c Client) name() string {
-	name, err := ewmh.WmNameGet(state.X, c.window.Id)
-	if err != nil {
-		return ""
-	}
-
-	return name
+	return backend.Name(c.window)
 }
 
+// MoveResize moves the client, unless it was already moved there.
 func (c Client) MoveResize(x, y, width, height int) {
+	geom := xrect.New(x, y, width, height)
+	if c.applied.geom != nil && sameRect(c.applied.geom, geom) {
+		return
+	}
+
 	c.Unmaximize()
 
 	dw, dh := c.DecorDimensions()
-	err := c.window.WMMoveResize(x, y, width-dw, height-dh)
+	err := backend.MoveResize(c.window, x, y, width-dw, height-dh)
 
 	if err != nil {
 		log.Info("Error when moving ", c.name(), " ", err)
+		c.applied.invalidate()
+		return
 	}
+	c.applied.geom = geom
 }
 
 // DecorDimensions returns the width and height occupied by window decorations
 func (c Client) DecorDimensions() (width int, height int) {
-	cGeom, err1 := xwindow.RawGeometry(state.X, xproto.Drawable(c.window.Id))
-	pGeom, err2 := c.window.DecorGeometry()
+	if c.applied.decorKnown {
+		return c.applied.decorWidth, c.applied.decorHeight
+	}
+
+	cGeom, err1 := backend.ClientGeometry(c.window)
+	pGeom, err2 := backend.Geometry(c.window)
 
 	if err1 != nil || err2 != nil {
 		return
@@ -80,20 +133,58 @@ func (c Client) DecorDimensions() (width int, height int) {
 
 	width = pGeom.Width() - cGeom.Width()
 	height = pGeom.Height() - cGeom.Height()
+	c.applied.decorKnown, c.applied.decorWidth, c.applied.decorHeight = true, width, height
+	c.applied.decorLeft, c.applied.decorTop = cGeom.X(), cGeom.Y()
 	return
 }
 
+// checkGeometry forgets the cached geometry of the client if it doesn't match the one reported by an event,
+// for example after the client was moved by the user or resized by the window manager.
+// It returns the frame of the client, or nil if the event doesn't tell where the frame is.
+func (c Client) checkGeometry(x, y, width, height int) (frame xrect.Rect) {
+	a := c.applied
+	if !a.decorKnown {
+		a.invalidate()
+		return nil
+	}
+
+	// A position relative to the frame says nothing about where the frame is.
+	if x != a.decorLeft || y != a.decorTop {
+		frame = xrect.New(x-a.decorLeft, y-a.decorTop, width+a.decorWidth, height+a.decorHeight)
+	}
+
+	if a.geom == nil {
+		return
+	}
+
+	// The decorations are kept after a move, so that the frames of the next events are known.
+	gw, gh := a.geom.Width(), a.geom.Height()
+	if width != gw-a.decorWidth || height != gh-a.decorHeight {
+		a.invalidate()
+	} else if frame != nil && !sameRect(frame, a.geom) {
+		a.geom = nil
+	}
+	return
+}
+
+func sameRect(a, b xrect.Rect) bool {
+	ax, ay, aw, ah := xrect.Pieces(a)
+	bx, by, bw, bh := xrect.Pieces(b)
+	return ax == bx && ay == by && aw == bw && ah == bh
+}
+
 func (c Client) Unmaximize() {
-	ewmh.WmStateReq(state.X, c.window.Id, 0, "_NET_WM_STATE_MAXIMIZED_VERT")
-	ewmh.WmStateReq(state.X, c.window.Id, 0, "_NET_WM_STATE_MAXIMIZED_HORZ")
+	backend.Unmaximize(c.window)
 }
 
 func (c Client) UnDecorate() {
-	motif.WmHintsSet(state.X, c.window.Id,
-		&motif.Hints{
-			Flags:      motif.HintDecorations,
-			Decoration: motif.DecorationNone,
-		})
+	if c.applied.undecorated {
+		return
+	}
+
+	backend.SetDecoration(c.window, false)
+	c.applied.undecorated = true
+	c.applied.invalidate()
 }
 
 func (c Client) Decorate() {
@@ -101,41 +192,35 @@ func (c Client) Decorate() {
 		return
 	}
 
-	motif.WmHintsSet(state.X, c.window.Id,
-		&motif.Hints{
-			Flags:      motif.HintDecorations,
-			Decoration: motif.DecorationAll,
-		})
+	backend.SetDecoration(c.window, true)
+	c.applied.undecorated = false
+	c.applied.invalidate()
 }
 
 // Restore resizes and decorates window to pre-tiling state.
+// The geometry is forgotten afterwards, since the window is free to be moved until it is tiled again.
 func (c Client) Restore() {
 	c.Decorate()
 	geom := c.savedProp.Geom
 	log.Info("Restoring ", c.name(), ": ", "X: ", geom.X(), " Y: ", geom.Y())
+	c.applied.invalidate()
 	c.MoveResize(geom.X(), geom.Y(), geom.Width(), geom.Height())
+	c.applied.invalidate()
 }
 
-//  Activate makes the client the currently active window
+// Activate makes the client the currently active window
 func (c Client) Activate() {
-	ewmh.ActiveWindowReq(state.X, c.window.Id)
+	backend.Activate(c.window)
 }
 
 // hasDecoration returns true if the window has client decorations.
 func hasDecoration(wid xproto.Window) bool {
-	mh, err := motif.WmHintsGet(state.X, wid)
-
-	if err != nil {
-		return true
-	}
-
-	return motif.Decor(mh)
+	return backend.HasDecoration(wid)
 }
 
 // isHidden returns true if the window has been minimized.
 func isHidden(w xproto.Window) bool {
-	states, _ := ewmh.WmStateGet(state.X, w)
-	for _, state := range states {
+	for _, state := range backend.States(w) {
 		if state == "_NET_WM_STATE_HIDDEN" {
 			return true
 		}
@@ -144,17 +229,38 @@ func isHidden(w xproto.Window) bool {
 	return false
 }
 
-func shouldIgnore(w xproto.Window) bool {
-	c, err := icccm.WmClassGet(state.X, w)
-	if err != nil {
-		log.Warn(err)
-	}
+// floatingTypes are the window types that are not tiled by default.
+var floatingTypes = []string{"DIALOG", "UTILITY", "SPLASH", "TOOLBAR", "MENU", "NOTIFICATION"}
 
-	for _, s := range Config.WindowsToIgnore {
-		if strings.EqualFold(c.Class, s) {
+// shouldFloat returns true for dialogs, transient windows and windows that can't be resized,
+// which are left floating unless a rule says otherwise.
+func shouldFloat(w xproto.Window) bool {
+	types := backend.Types(w)
+	for _, t := range floatingTypes {
+		if hasWindowType(types, t) {
 			return true
 		}
 	}
 
-	return false
+	if backend.TransientFor(w) != 0 {
+		return true
+	}
+
+	return backend.SizeHints(w).isFixed()
+}
+
+// CenterOnParent moves a transient client to the center of the window it belongs to.
+func (c Client) CenterOnParent() {
+	parent := backend.TransientFor(c.window)
+	if parent == 0 {
+		return
+	}
+
+	pGeom, err1 := backend.Geometry(parent)
+	cGeom, err2 := backend.Geometry(c.window)
+	if err1 != nil || err2 != nil {
+		return
+	}
+
+	backend.Move(c.window, pGeom.X()+(pGeom.Width()-cGeom.Width())/2, pGeom.Y()+(pGeom.Height()-cGeom.Height())/2)
 }
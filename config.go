@@ -1,28 +1,54 @@
 package main
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
-	"github.com/BurntSushi/toml"
+	"github.com/blrsn/zentile/state"
 	"github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
 )
 
 var Config cfg
 
 type cfg struct {
 	Keybindings     map[string]string
-	WindowsToIgnore []string `toml:"ignore"`
+	WindowsToIgnore []string `toml:"ignore"` // Deprecated, converted to ignore rules.
+	Rules           []Rule   `toml:"rule"`
 	Gap             int
 	Proportion      float64
 	HideDecor       bool `toml:"remove_decorations"`
+	Sticky          string
 }
 
 func init() {
 	writeDefaultConfig()
-	toml.DecodeFile(configFilePath(), &Config)
+}
+
+// loadConfig reads and validates the config file, and makes it the current Config.
+// Config is left untouched if the file has fatal problems, which are returned as error.
+func loadConfig() error {
+	c, problems := readConfig(state.X)
+
+	var fatal []string
+	for _, p := range problems {
+		if p.fatal {
+			fatal = append(fatal, p.String())
+		} else {
+			log.Warn(p)
+		}
+	}
+
+	if len(fatal) > 0 {
+		return errors.New(strings.Join(fatal, "\n"))
+	}
+
+	Config = c
+	return nil
 }
 
 func writeDefaultConfig() {
@@ -59,10 +85,33 @@ func configFilePath() string {
 var defaultConfig = `# Window decorations will be removed when tiling if set to true
 remove_decorations = false
 
-# Zentile will ignore windows added to this list.
-# You'll have to add WM_CLASS property of the window you want ignored.
-# You can get WM_CLASS property of a window, by running "xprop WM_CLASS" and clicking on the window.
-# ignore = ['ulauncher', 'gnome-screenshot']
+# Rules change how zentile treats the windows they match.
+# A rule matches a window if all of its criteria match:
+#   class, instance: WM_CLASS of the window, run "xprop WM_CLASS" and click on the window to get it.
+#   title:           a regular expression matched against the window title.
+#   role:            WM_WINDOW_ROLE of the window.
+#   type:            _NET_WM_WINDOW_TYPE of the window, e.g. "dialog" or "utility".
+# Actions of the matching rules are then applied:
+#   ignore = true          zentile leaves the window alone.
+#   float = true           the window is not tiled. Dialogs, splash screens, utility and
+#                          transient windows float by default, "float = false" tiles them.
+#   workspace = 2          the window is moved to a workspace, counting from 0.
+#   master = true          the window is always added as a master.
+#   stack = true           the window is never made master automatically.
+#   width, height          the window floats with a fixed size.
+#   no_decorations = true  the window decorations are removed.
+#
+# [[rule]]
+# class = 'ulauncher'
+# ignore = true
+#
+# [[rule]]
+# class = 'zoom'
+# float = true
+
+# Windows shown on all workspaces are left floating with "float",
+# or tiled in whichever workspace is current with "tile".
+sticky = "float"
 
 # Adds spacing between windows
 gap = 5
@@ -105,4 +154,10 @@ increment_master = "Control-bracketright"
 
 # Decreases the size of the master windows.
 decrement_master = "Control-bracketleft"
+
+# Takes the active window out of the tiling, or puts it back in.
+toggle_float = "Control-Shift-f"
+
+# The config is reloaded whenever this file is saved, this reloads it manually.
+# reload_config = "Control-Shift-r"
 `
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil/xrect"
+)
+
+// fakeBackend is an in-memory Backend, for testing the layouts and the tracker without an X server.
+// Windows are decorated with a border on every side and a title bar on top.
+type fakeBackend struct {
+	windows    map[xproto.Window]*fakeWindow
+	stacking   []xproto.Window
+	areas      []xrect.Rect // Work area of every head, the same on every desktop.
+	activeHead uint
+	border     int
+	title      int
+	handlers   map[xproto.Window]map[string]func()
+	geometry   map[xproto.Window]func(x, y, width, height int)
+	heads      func()
+	lastId     xproto.Window
+}
+
+// fakeWindow is a window of the fake backend.
+type fakeWindow struct {
+	x, y          int // Position of the frame.
+	width, height int // Size of the window, excluding decorations.
+	desk          uint
+	class         string
+	instance      string
+	name          string
+	role          string
+	types         []string
+	states        []string
+	transientFor  xproto.Window
+	hints         sizeHints
+	decorated     bool
+}
+
+const fakeRoot xproto.Window = 1
+
+var errNoWindow = errors.New("no such window")
+
+// newFakeBackend creates a fake backend with a head for each work area.
+func newFakeBackend(areas ...xrect.Rect) *fakeBackend {
+	return &fakeBackend{
+		windows:  make(map[xproto.Window]*fakeWindow),
+		areas:    areas,
+		border:   1,
+		title:    20,
+		handlers: make(map[xproto.Window]map[string]func()),
+		geometry: make(map[xproto.Window]func(x, y, width, height int)),
+		lastId:   0x100,
+	}
+}
+
+// AddWindow maps a window on top of the others and returns its id.
+func (b *fakeBackend) AddWindow(fw fakeWindow) xproto.Window {
+	b.lastId++
+	b.windows[b.lastId] = &fw
+	b.stacking = append(b.stacking, b.lastId)
+	b.notify(fakeRoot, "_NET_CLIENT_LIST_STACKING")
+	return b.lastId
+}
+
+// RemoveWindow unmaps the window.
+func (b *fakeBackend) RemoveWindow(w xproto.Window) {
+	delete(b.windows, w)
+	for i, s := range b.stacking {
+		if s == w {
+			b.stacking = append(b.stacking[:i], b.stacking[i+1:]...)
+			break
+		}
+	}
+	b.notify(fakeRoot, "_NET_CLIENT_LIST_STACKING")
+}
+
+// SetAreas replaces the heads by one for each work area.
+func (b *fakeBackend) SetAreas(areas ...xrect.Rect) {
+	b.areas = areas
+	if b.heads != nil {
+		b.heads()
+	}
+}
+
+// SetStates replaces the _NET_WM_STATE of the window.
+func (b *fakeBackend) SetStates(w xproto.Window, states ...string) {
+	if fw, ok := b.windows[w]; ok {
+		fw.states = states
+		b.notify(w, "_NET_WM_STATE")
+	}
+}
+
+func (b *fakeBackend) notify(w xproto.Window, prop string) {
+	if f, ok := b.handlers[w][prop]; ok {
+		f()
+	}
+}
+
+// notifyGeometry reports the geometry of the window like X does,
+// relative to the frame and then in root coordinates, as sent by the window manager.
+func (b *fakeBackend) notifyGeometry(w xproto.Window) {
+	f, ok := b.geometry[w]
+	if !ok {
+		return
+	}
+
+	fw := b.windows[w]
+	left, top := 0, 0
+	if fw.decorated {
+		left, top = b.border, b.title+b.border
+	}
+	f(left, top, fw.width, fw.height)
+	f(fw.x+left, fw.y+top, fw.width, fw.height)
+}
+
+// decorations returns the size taken by the decorations of the window.
+func (b *fakeBackend) decorations(fw *fakeWindow) (width, height int) {
+	if !fw.decorated {
+		return 0, 0
+	}
+	return 2 * b.border, b.title + 2*b.border
+}
+
+func (b *fakeBackend) Geometry(w xproto.Window) (xrect.Rect, error) {
+	fw, ok := b.windows[w]
+	if !ok {
+		return nil, errNoWindow
+	}
+
+	dw, dh := b.decorations(fw)
+	return xrect.New(fw.x, fw.y, fw.width+dw, fw.height+dh), nil
+}
+
+func (b *fakeBackend) ClientGeometry(w xproto.Window) (xrect.Rect, error) {
+	fw, ok := b.windows[w]
+	if !ok {
+		return nil, errNoWindow
+	}
+
+	// Like X, the position is relative to the frame.
+	if !fw.decorated {
+		return xrect.New(0, 0, fw.width, fw.height), nil
+	}
+	return xrect.New(b.border, b.title+b.border, fw.width, fw.height), nil
+}
+
+func (b *fakeBackend) Desktop(w xproto.Window) (uint, error) {
+	fw, ok := b.windows[w]
+	if !ok {
+		return 0, errNoWindow
+	}
+	return fw.desk, nil
+}
+
+func (b *fakeBackend) Class(w xproto.Window) (string, string, error) {
+	fw, ok := b.windows[w]
+	if !ok {
+		return "", "", errNoWindow
+	}
+	return fw.class, fw.instance, nil
+}
+
+func (b *fakeBackend) Name(w xproto.Window) string {
+	if fw, ok := b.windows[w]; ok {
+		return fw.name
+	}
+	return ""
+}
+
+func (b *fakeBackend) Role(w xproto.Window) string {
+	if fw, ok := b.windows[w]; ok {
+		return fw.role
+	}
+	return ""
+}
+
+func (b *fakeBackend) Types(w xproto.Window) []string {
+	if fw, ok := b.windows[w]; ok {
+		return fw.types
+	}
+	return nil
+}
+
+func (b *fakeBackend) States(w xproto.Window) []string {
+	if fw, ok := b.windows[w]; ok {
+		return fw.states
+	}
+	return nil
+}
+
+func (b *fakeBackend) TransientFor(w xproto.Window) xproto.Window {
+	if fw, ok := b.windows[w]; ok {
+		return fw.transientFor
+	}
+	return 0
+}
+
+func (b *fakeBackend) SizeHints(w xproto.Window) sizeHints {
+	if fw, ok := b.windows[w]; ok {
+		return fw.hints
+	}
+	return sizeHints{}
+}
+
+func (b *fakeBackend) HasDecoration(w xproto.Window) bool {
+	if fw, ok := b.windows[w]; ok {
+		return fw.decorated
+	}
+	return true
+}
+
+func (b *fakeBackend) MoveResize(w xproto.Window, x, y, width, height int) error {
+	fw, ok := b.windows[w]
+	if !ok {
+		return errNoWindow
+	}
+
+	fw.x, fw.y, fw.width, fw.height = x, y, width, height
+	b.notifyGeometry(w)
+	return nil
+}
+
+func (b *fakeBackend) Move(w xproto.Window, x, y int) {
+	if fw, ok := b.windows[w]; ok {
+		fw.x, fw.y = x, y
+		b.notifyGeometry(w)
+	}
+}
+
+func (b *fakeBackend) Resize(w xproto.Window, width, height int) {
+	if fw, ok := b.windows[w]; ok {
+		fw.width, fw.height = width, height
+		b.notifyGeometry(w)
+	}
+}
+
+func (b *fakeBackend) SetDecoration(w xproto.Window, decorated bool) {
+	if fw, ok := b.windows[w]; ok && fw.decorated != decorated {
+		fw.decorated = decorated
+		b.notifyGeometry(w)
+	}
+}
+
+func (b *fakeBackend) Unmaximize(w xproto.Window) {
+	if fw, ok := b.windows[w]; ok {
+		var states []string
+		for _, s := range fw.states {
+			if s != "_NET_WM_STATE_MAXIMIZED_VERT" && s != "_NET_WM_STATE_MAXIMIZED_HORZ" {
+				states = append(states, s)
+			}
+		}
+		fw.states = states
+	}
+}
+
+func (b *fakeBackend) Activate(w xproto.Window) {}
+
+func (b *fakeBackend) SetDesktop(w xproto.Window, desk uint) {
+	if fw, ok := b.windows[w]; ok && fw.desk != desk {
+		fw.desk = desk
+		b.notify(w, "_NET_WM_DESKTOP")
+	}
+}
+
+func (b *fakeBackend) Sync() {}
+
+func (b *fakeBackend) Clients() []xproto.Window {
+	return append([]xproto.Window(nil), b.stacking...)
+}
+
+func (b *fakeBackend) WorkArea(desk, head uint) (x, y, width, height int) {
+	if len(b.areas) == 0 {
+		return
+	}
+
+	if head >= uint(len(b.areas)) {
+		head = uint(len(b.areas)) - 1
+	}
+	return xrect.Pieces(b.areas[head])
+}
+
+func (b *fakeBackend) ActiveHead() uint {
+	return b.activeHead
+}
+
+func (b *fakeBackend) HeadCount() uint {
+	return uint(len(b.areas))
+}
+
+func (b *fakeBackend) HeadForRect(r xrect.Rect) uint {
+	if r == nil {
+		return 0
+	}
+
+	cx, cy := r.X()+r.Width()/2, r.Y()+r.Height()/2
+	for i, h := range b.areas {
+		if cx >= h.X() && cx < h.X()+h.Width() && cy >= h.Y() && cy < h.Y()+h.Height() {
+			return uint(i)
+		}
+	}
+
+	if i := xrect.LargestOverlap(r, b.areas); i >= 0 {
+		return uint(i)
+	}
+	return 0
+}
+
+func (b *fakeBackend) Watch(w xproto.Window, handlers map[string]func()) {
+	b.handlers[w] = handlers
+}
+
+func (b *fakeBackend) WatchGeometry(w xproto.Window, f func(x, y, width, height int)) {
+	b.geometry[w] = f
+}
+
+func (b *fakeBackend) WatchHeads(f func()) {
+	b.heads = f
+}
+
+func (b *fakeBackend) Unwatch(w xproto.Window) {
+	delete(b.handlers, w)
+	delete(b.geometry, w)
+}
+
+func (b *fakeBackend) Root() xproto.Window {
+	return fakeRoot
+}
@@ -1,21 +1,33 @@
 package main
 
 import (
-	"github.com/blrsn/zentile/state"
+	"github.com/BurntSushi/xgbutil/xrect"
 	log "github.com/sirupsen/logrus"
 )
 
 type FullScreen struct {
 	*Store
 	WorkspaceNum uint
+	HeadNum      uint
+}
+
+func (fs *FullScreen) Name() string {
+	return "fullscreen"
 }
 
 func (fs *FullScreen) Do() {
 	log.Info("Switching to Fullscreen layout")
-	for _, c := range fs.Store.All() {
-		x, y, w, h := state.WorkAreaDimensions(fs.WorkspaceNum)
-		c.MoveResize(x, y, w, h)
+	// Decorations are kept, the clients are stacked on top of each other.
+	applyLayout(fs, fs.WorkspaceNum, fs.HeadNum)
+}
+
+// geometry gives every client the whole work area, they are stacked on top of each other.
+func (fs *FullScreen) geometry(a arrangement) []xrect.Rect {
+	rects := make([]xrect.Rect, len(a.masters)+len(a.slaves))
+	for i := range rects {
+		rects[i] = a.area
 	}
+	return rects
 }
 
 func (fs *FullScreen) Undo() {
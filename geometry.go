@@ -0,0 +1,133 @@
+package main
+
+import (
+	"github.com/BurntSushi/xgbutil/xrect"
+)
+
+// tile is what the geometry of a layout needs to know about a client.
+type tile struct {
+	hints                   sizeHints
+	decorWidth, decorHeight int
+}
+
+// arrangement is the input of the geometry of a layout.
+// The geometry only depends on it and the parameters of the layout,
+// so it can be computed without moving any window.
+type arrangement struct {
+	area    xrect.Rect
+	masters []tile
+	slaves  []tile
+	gap     int
+}
+
+// fit returns the largest size within width and height,
+// including decorations, that satisfies the size hints of the client.
+func (t tile) fit(width, height int) (int, int) {
+	return t.hints.width.fit(width-t.decorWidth) + t.decorWidth,
+		t.hints.height.fit(height-t.decorHeight) + t.decorHeight
+}
+
+func (a arrangement) all() []tile {
+	return append(append([]tile(nil), a.masters...), a.slaves...)
+}
+
+// arrange returns the arrangement of the clients of a layout in the work area of its head.
+func arrange(st *Store, workspaceNum, headNum uint) arrangement {
+	return arrangement{
+		area:    xrect.New(backend.WorkArea(workspaceNum, headNum)),
+		masters: tiles(st.masters),
+		slaves:  tiles(st.slaves),
+		gap:     Config.Gap,
+	}
+}
+
+func tiles(clients []Client) []tile {
+	ts := make([]tile, len(clients))
+	for i, c := range clients {
+		dw, dh := c.DecorDimensions()
+		ts[i] = tile{c.hints, dw, dh}
+	}
+	return ts
+}
+
+// applyLayout moves the clients of the layout to the geometry it computes,
+// the masters first, then the slaves.
+// Layouts that remove decorations do so beforehand, since they are part of the size of the clients.
+func applyLayout(l Layout, workspaceNum, headNum uint) {
+	st := l.sto()
+	clients := st.All()
+	if len(clients) == 0 {
+		return
+	}
+
+	for i, r := range l.geometry(arrange(st, workspaceNum, headNum)) {
+		clients[i].MoveResize(r.Pieces())
+	}
+
+	backend.Sync()
+}
+
+// prepareTiling removes the decorations of the clients if configured.
+func prepareTiling(clients []Client) {
+	if !Config.HideDecor {
+		return
+	}
+
+	for _, c := range clients {
+		c.UnDecorate()
+	}
+}
+
+// column places the tiles on top of each other in the given area,
+// with gaps between them.
+func column(tiles []tile, x, y, width, height, gap int) []xrect.Rect {
+	n := len(tiles)
+	if n == 0 {
+		return nil
+	}
+
+	heights := make([]int, n)
+	for i := range heights {
+		heights[i] = (height - (n-1)*gap) / n
+	}
+
+	widths := make([]int, n)
+	fitStack(heights, func(i, size int) (h int) {
+		widths[i], h = tiles[i].fit(width, size)
+		return
+	})
+
+	rects := make([]xrect.Rect, n)
+	for i := range rects {
+		rects[i] = xrect.New(x, y, widths[i], heights[i])
+		y += heights[i] + gap
+	}
+	return rects
+}
+
+// row places the tiles next to each other in the given area,
+// with gaps between them.
+func row(tiles []tile, x, y, width, height, gap int) []xrect.Rect {
+	n := len(tiles)
+	if n == 0 {
+		return nil
+	}
+
+	widths := make([]int, n)
+	for i := range widths {
+		widths[i] = (width - (n-1)*gap) / n
+	}
+
+	heights := make([]int, n)
+	fitStack(widths, func(i, size int) (w int) {
+		w, heights[i] = tiles[i].fit(size, height)
+		return
+	})
+
+	rects := make([]xrect.Rect, n)
+	for i := range rects {
+		rects[i] = xrect.New(x, y, widths[i], heights[i])
+		x += widths[i] + gap
+	}
+	return rects
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/xgbutil/xrect"
+)
+
+func TestLayoutGeometry(t *testing.T) {
+	vertHorz := &VertHorz{Store: buildStore(), Proportion: 0.5}
+	area := xrect.New(0, 0, 1000, 800)
+	short := tile{hints: sizeHints{height: axisHints{max: 200}}, decorWidth: 2, decorHeight: 22}
+
+	tests := []struct {
+		layout  Layout
+		masters []tile
+		slaves  []tile
+		want    []xrect.Rect
+	}{
+		{
+			layout:  &VerticalLayout{vertHorz},
+			masters: make([]tile, 1),
+			want:    []xrect.Rect{xrect.New(10, 10, 980, 780)},
+		},
+		{
+			layout:  &VerticalLayout{vertHorz},
+			masters: make([]tile, 1),
+			slaves:  make([]tile, 2),
+			want: []xrect.Rect{
+				xrect.New(10, 10, 480, 780),
+				xrect.New(500, 10, 490, 385),
+				xrect.New(500, 405, 490, 385),
+			},
+		},
+		{
+			// The space a client can't use goes to the next one.
+			layout:  &VerticalLayout{vertHorz},
+			masters: make([]tile, 1),
+			slaves:  []tile{short, {}},
+			want: []xrect.Rect{
+				xrect.New(10, 10, 480, 780),
+				xrect.New(500, 10, 490, 222),
+				xrect.New(500, 242, 490, 548),
+			},
+		},
+		{
+			layout:  &HorizontalLayout{vertHorz},
+			masters: make([]tile, 1),
+			slaves:  make([]tile, 2),
+			want: []xrect.Rect{
+				xrect.New(10, 10, 980, 380),
+				xrect.New(10, 400, 485, 390),
+				xrect.New(505, 400, 485, 390),
+			},
+		},
+		{
+			layout:  &CenterLayout{vertHorz},
+			masters: make([]tile, 1),
+			slaves:  make([]tile, 1),
+			want: []xrect.Rect{
+				xrect.New(10, 10, 480, 780),
+				xrect.New(500, 10, 490, 780),
+			},
+		},
+		{
+			layout:  &CenterLayout{vertHorz},
+			masters: make([]tile, 1),
+			slaves:  make([]tile, 2),
+			want: []xrect.Rect{
+				xrect.New(260, 10, 480, 780),
+				xrect.New(750, 10, 240, 780),
+				xrect.New(10, 10, 240, 780),
+			},
+		},
+		{
+			layout:  &SpiralLayout{vertHorz},
+			masters: make([]tile, 1),
+			slaves:  make([]tile, 4),
+			want: []xrect.Rect{
+				xrect.New(10, 10, 485, 780),
+				xrect.New(505, 10, 485, 385),
+				xrect.New(753, 405, 237, 385),
+				xrect.New(505, 603, 238, 187),
+				xrect.New(505, 405, 238, 188),
+			},
+		},
+		{
+			layout:  &GridLayout{Store: buildStore()},
+			masters: make([]tile, 1),
+			slaves:  make([]tile, 2),
+			want: []xrect.Rect{
+				xrect.New(10, 10, 485, 385),
+				xrect.New(505, 10, 485, 385),
+				xrect.New(10, 405, 980, 385),
+			},
+		},
+		{
+			layout:  &FullScreen{Store: buildStore()},
+			masters: make([]tile, 1),
+			slaves:  make([]tile, 1),
+			want:    []xrect.Rect{area, area},
+		},
+	}
+
+	for _, test := range tests {
+		got := test.layout.geometry(arrangement{area: area, masters: test.masters, slaves: test.slaves, gap: 10})
+		if len(got) != len(test.want) {
+			t.Errorf("%s with %d clients: got %d rectangles", test.layout.Name(), len(test.want), len(got))
+			continue
+		}
+
+		for i := range got {
+			if !sameRect(got[i], test.want[i]) {
+				t.Errorf("%s with %d clients: client %d is at %v, want %v", test.layout.Name(), len(test.want), i, got[i], test.want[i])
+			}
+		}
+	}
+}
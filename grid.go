@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+
+	"github.com/BurntSushi/xgbutil/xrect"
+	log "github.com/sirupsen/logrus"
+)
+
+// GridLayout arranges all the clients into a near-square grid of equal cells.
+type GridLayout struct {
+	*Store
+	WorkspaceNum uint
+	HeadNum      uint
+}
+
+func (l *GridLayout) Name() string {
+	return "grid"
+}
+
+func (l *GridLayout) Do() {
+	log.Info("Switching to Grid layout")
+	prepareTiling(l.All())
+	applyLayout(l, l.WorkspaceNum, l.HeadNum)
+}
+
+func (l *GridLayout) geometry(a arrangement) []xrect.Rect {
+	tiles := a.all()
+	csize := len(tiles)
+	if csize == 0 {
+		return nil
+	}
+
+	wx, wy, ww, wh := a.area.Pieces()
+	cols, rows := gridDimensions(csize)
+	gap := a.gap
+	ch := (wh - (rows+1)*gap) / rows
+
+	// The last row might be partially filled, its cells share the full width.
+	var rects []xrect.Rect
+	for r := 0; r < rows; r++ {
+		end := (r + 1) * cols
+		if end > csize {
+			end = csize
+		}
+		rects = append(rects, row(tiles[r*cols:end], wx+gap, gap+wy+r*(ch+gap), ww-2*gap, ch, gap)...)
+	}
+
+	return rects
+}
+
+// gridDimensions returns the number of columns and rows needed to fit n cells.
+func gridDimensions(n int) (cols, rows int) {
+	cols = int(math.Ceil(math.Sqrt(float64(n))))
+	rows = int(math.Ceil(float64(n) / float64(cols)))
+	return
+}
+
+func (l *GridLayout) Undo() {
+	for _, c := range l.All() {
+		c.Restore()
+	}
+}
+
+func (l *GridLayout) NextClient() {
+	l.Next().Activate()
+}
+
+func (l *GridLayout) PreviousClient() {
+	l.Previous().Activate()
+}
+
+func (l *GridLayout) IncrementMaster() {
+}
+
+func (l *GridLayout) DecrementMaster() {
+}
+
+func (l *GridLayout) sto() *Store {
+	return l.Store
+}
@@ -0,0 +1,47 @@
+package main
+
+// sizeHints are the ICCCM size constraints of a client window, excluding decorations.
+type sizeHints struct {
+	width, height axisHints
+}
+
+// axisHints are the size constraints along one axis. Zero values mean unconstrained.
+type axisHints struct {
+	min, max, base, inc int
+}
+
+// isFixed returns true if the hints don't allow resizing.
+func (h sizeHints) isFixed() bool {
+	return h.width.max > 0 && h.width.min == h.width.max &&
+		h.height.max > 0 && h.height.min == h.height.max
+}
+
+// fit returns the largest size not exceeding size that is a whole number of increments,
+// clamped to the minimum and maximum size.
+func (a axisHints) fit(size int) int {
+	if a.inc > 1 && size > a.base {
+		size = a.base + (size-a.base)/a.inc*a.inc
+	}
+
+	if a.max > 0 && size > a.max {
+		size = a.max
+	}
+
+	if size < a.min {
+		size = a.min
+	}
+
+	return size
+}
+
+// fitStack adjusts the sizes of the cells in a stack to the size hints of their clients.
+// Space that a client can't use is handed to the next cell, and space taken beyond
+// its cell is taken from the next one, so that the gaps between the cells stay the same.
+func fitStack(sizes []int, fit func(i, size int) int) {
+	carry := 0
+	for i := range sizes {
+		want := sizes[i] + carry
+		sizes[i] = fit(i, want)
+		carry = want - sizes[i]
+	}
+}
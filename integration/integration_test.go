@@ -0,0 +1,229 @@
+//go:build integration
+// +build integration
+
+// Package integration runs zentile against a real X server and checks the geometry of the tiled windows.
+//
+// It starts Xvfb, an EWMH compliant window manager and zentile, opens dummy windows,
+// drives zentile through its IPC socket and compares the window frames reported by
+// the X server with the expected tiles. Everything runs offline, on a display of its own.
+//
+//	go test -tags integration ./integration
+package integration
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/xgbutil"
+	"github.com/BurntSushi/xgbutil/ewmh"
+	"github.com/BurntSushi/xgbutil/xevent"
+	"github.com/blrsn/zentile/ipc"
+)
+
+var (
+	zentilePath = flag.String("zentile", "", "zentile binary to test, built from the source tree if empty")
+	display     = flag.String("display", ":99", "display for Xvfb")
+	wm          = flag.String("wm", "openbox", "EWMH compliant window manager to run zentile with")
+	keep        = flag.Bool("keep", false, "keep the temporary folder with the logs and config")
+)
+
+// testGap is the gap between windows in the test config.
+const testGap = 10
+
+// testConfig is the zentile config used by the tests. Windows keep their decorations,
+// so that the decoration math is exercised.
+var testConfig = fmt.Sprintf(`
+gap = %d
+proportion = 0.1
+remove_decorations = false
+`, testGap)
+
+// timeout is how long to wait for the X server, the window manager or zentile to catch up.
+const timeout = 5 * time.Second
+
+func TestIntegration(t *testing.T) {
+	for _, command := range []string{"Xvfb", *wm} {
+		if _, err := exec.LookPath(command); err != nil {
+			t.Skipf("%s is not installed", command)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "zentile-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if *keep || t.Failed() {
+			t.Log("Logs are in", dir)
+		} else {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	h, stopAll, err := start(dir)
+	defer stopAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Scenarios build on the windows of the previous ones, the remaining ones run after a failure.
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.run(h); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// start builds zentile if needed and starts the processes.
+// The returned function stops them, also when an error is returned.
+func start(dir string) (*harness, func(), error) {
+	var cmds []*exec.Cmd
+	stopAll := func() {
+		for i := len(cmds) - 1; i >= 0; i-- {
+			stop(cmds[i])
+		}
+	}
+
+	env, err := setupEnv(dir)
+	if err != nil {
+		return nil, stopAll, err
+	}
+
+	zentile := *zentilePath
+	if zentile == "" {
+		zentile = filepath.Join(dir, "zentile")
+		if out, err := exec.Command("go", "build", "-o", zentile, "github.com/blrsn/zentile").CombinedOutput(); err != nil {
+			return nil, stopAll, fmt.Errorf("Error building zentile: %v\n%s", err, out)
+		}
+	}
+
+	xvfb, err := run(dir, "xvfb", env, "Xvfb", *display, "-screen", "0", "1280x800x24", "-nolisten", "tcp")
+	if err != nil {
+		return nil, stopAll, err
+	}
+	cmds = append(cmds, xvfb)
+
+	X, err := waitForDisplay()
+	if err != nil {
+		return nil, stopAll, err
+	}
+	go xevent.Main(X)
+
+	wmCmd, err := run(dir, "wm", env, *wm)
+	if err != nil {
+		return nil, stopAll, err
+	}
+	cmds = append(cmds, wmCmd)
+
+	if err := waitFor(func() bool { _, err := ewmh.GetEwmhWM(X); return err == nil }); err != nil {
+		return nil, stopAll, errors.New("window manager did not start")
+	}
+
+	zentileCmd, err := run(dir, "zentile", env, zentile)
+	if err != nil {
+		return nil, stopAll, err
+	}
+	cmds = append(cmds, zentileCmd)
+
+	if err := waitFor(func() bool { _, err := ipc.Send(ipc.Request{Action: "get_workspaces"}); return err == nil }); err != nil {
+		return nil, stopAll, errors.New("zentile did not start")
+	}
+
+	return newHarness(X), stopAll, nil
+}
+
+// setupEnv returns the environment of the started processes, with config, state and socket in dir.
+// The harness itself uses the same socket.
+func setupEnv(dir string) ([]string, error) {
+	configDir := filepath.Join(dir, "config")
+	if err := os.MkdirAll(filepath.Join(configDir, "zentile"), 0700); err != nil {
+		return nil, err
+	}
+
+	err := ioutil.WriteFile(filepath.Join(configDir, "zentile", "config.toml"), []byte(testConfig), 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := map[string]string{
+		"DISPLAY":         *display,
+		"HOME":            dir,
+		"XDG_CONFIG_HOME": configDir,
+		"XDG_STATE_HOME":  filepath.Join(dir, "state"),
+		"XDG_RUNTIME_DIR": dir,
+	}
+
+	env := os.Environ()
+	for k, v := range vars {
+		os.Setenv(k, v)
+		env = append(env, k+"="+v)
+	}
+	return env, nil
+}
+
+// run starts a command in the background, logging its output to dir/name.log.
+func run(dir, name string, env []string, command string, args ...string) (*exec.Cmd, error) {
+	logFile, err := os.Create(filepath.Join(dir, name+".log"))
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(command, args...)
+	cmd.Env = env
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("Error starting %s: %v", command, err)
+	}
+	return cmd, nil
+}
+
+func stop(cmd *exec.Cmd) {
+	cmd.Process.Signal(os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+	}
+}
+
+func waitForDisplay() (*xgbutil.XUtil, error) {
+	var X *xgbutil.XUtil
+	err := waitFor(func() bool {
+		var err error
+		X, err = xgbutil.NewConnDisplay(*display)
+		return err == nil
+	})
+
+	if err != nil {
+		return nil, errors.New("Xvfb did not start")
+	}
+	return X, nil
+}
+
+// waitFor polls until ok returns true, or fails after the timeout.
+func waitFor(ok func() bool) error {
+	deadline := time.Now().Add(timeout)
+	for !ok() {
+		if time.Now().After(deadline) {
+			return errors.New("timed out")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil"
+	"github.com/BurntSushi/xgbutil/ewmh"
+	"github.com/BurntSushi/xgbutil/icccm"
+	"github.com/BurntSushi/xgbutil/xrect"
+	"github.com/BurntSushi/xgbutil/xwindow"
+	"github.com/blrsn/zentile/ipc"
+)
+
+// scenario is a step of the test run. Scenarios run in order and build on the windows of the previous ones.
+type scenario struct {
+	name string
+	run  func(h *harness) error
+}
+
+var scenarios = []scenario{
+	{"tile workspace", func(h *harness) error {
+		for i := 0; i < 3; i++ {
+			if _, err := h.openWindow(); err != nil {
+				return err
+			}
+		}
+
+		if err := h.waitForClients(0, 3); err != nil {
+			return err
+		}
+		h.saveFrames()
+
+		if err := h.send("tile", "0"); err != nil {
+			return err
+		}
+		return h.checkVertical(0)
+	}},
+
+	{"new window is tiled", func(h *harness) error {
+		if _, err := h.openWindow(); err != nil {
+			return err
+		}
+
+		if err := h.waitForClients(0, 4); err != nil {
+			return err
+		}
+		return h.checkVertical(0)
+	}},
+
+	{"window moved to another desktop", func(h *harness) error {
+		w := h.windows[1]
+		ewmh.WmDesktopReq(h.X, w.Id, 1)
+		h.X.Sync()
+
+		if err := h.waitForClients(1, 1); err != nil {
+			return err
+		}
+
+		if err := h.checkVertical(0); err != nil {
+			return err
+		}
+
+		// The other desktop is not tiling, so the window gets its geometry back.
+		return h.checkFrame(w.Id, h.frames[w.Id])
+	}},
+
+	{"window closed", func(h *harness) error {
+		w := h.windows[0]
+		w.Destroy()
+		h.X.Sync()
+		h.windows = h.windows[1:]
+
+		if err := h.waitForClients(0, 2); err != nil {
+			return err
+		}
+		return h.checkVertical(0)
+	}},
+
+	{"untile workspace", func(h *harness) error {
+		if err := h.send("untile", "0"); err != nil {
+			return err
+		}
+
+		// Windows opened after tiling started are restored to a geometry that is unknown here.
+		for _, w := range h.windows {
+			if frame, ok := h.frames[w.Id]; ok {
+				if err := h.checkFrame(w.Id, frame); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}},
+}
+
+// harness holds the dummy windows and the geometry they had before they were tiled.
+type harness struct {
+	X       *xgbutil.XUtil
+	windows []*xwindow.Window
+	frames  map[xproto.Window]xrect.Rect
+}
+
+func newHarness(X *xgbutil.XUtil) *harness {
+	return &harness{X: X, frames: make(map[xproto.Window]xrect.Rect)}
+}
+
+// openWindow maps a plain window without size hints.
+func (h *harness) openWindow() (*xwindow.Window, error) {
+	w, err := xwindow.Generate(h.X)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Create(h.X.RootWin(), 100, 100, 300, 200, xproto.CwBackPixel, 0xffffff)
+	icccm.WmClassSet(h.X, w.Id, &icccm.WmClass{Instance: "dummy", Class: "Dummy"})
+	name := fmt.Sprintf("dummy %d", len(h.windows)+1)
+	ewmh.WmNameSet(h.X, w.Id, name)
+	icccm.WmNameSet(h.X, w.Id, name)
+	w.Map()
+	h.X.Sync()
+
+	h.windows = append(h.windows, w)
+	return w, nil
+}
+
+// saveFrames records the geometry of the windows before they are tiled.
+func (h *harness) saveFrames() {
+	for _, w := range h.windows {
+		if geom, err := w.DecorGeometry(); err == nil {
+			h.frames[w.Id] = geom
+		}
+	}
+}
+
+// send runs an action of zentile.
+func (h *harness) send(action string, args ...string) error {
+	resp, err := ipc.Send(ipc.Request{Action: action, Args: args})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("%s failed: %s", action, resp.Error)
+	}
+	return nil
+}
+
+// The parts of the get_tree response that are checked.
+type workspace struct {
+	Num      uint      `json:"num"`
+	IsTiling bool      `json:"is_tiling"`
+	Monitors []monitor `json:"monitors"`
+}
+
+type monitor struct {
+	Layout     string   `json:"layout"`
+	Proportion float64  `json:"proportion"`
+	Masters    []client `json:"masters"`
+	Slaves     []client `json:"slaves"`
+}
+
+type client struct {
+	Id uint32 `json:"id"`
+}
+
+func (h *harness) workspace(num uint) (workspace, error) {
+	resp, err := ipc.Send(ipc.Request{Action: "get_tree"})
+	if err != nil {
+		return workspace{}, err
+	}
+
+	var tree []workspace
+	data, _ := json.Marshal(resp.Data)
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return workspace{}, err
+	}
+
+	for _, ws := range tree {
+		if ws.Num == num {
+			return ws, nil
+		}
+	}
+	return workspace{}, fmt.Errorf("no workspace %d", num)
+}
+
+// waitForClients waits until the first monitor of the workspace has n clients.
+func (h *harness) waitForClients(num uint, n int) error {
+	count := 0
+	err := waitFor(func() bool {
+		ws, err := h.workspace(num)
+		if err != nil || len(ws.Monitors) == 0 {
+			return false
+		}
+
+		count = len(ws.Monitors[0].Masters) + len(ws.Monitors[0].Slaves)
+		return count == n
+	})
+
+	if err != nil {
+		return fmt.Errorf("workspace %d has %d clients, want %d", num, count, n)
+	}
+	return nil
+}
+
+// checkFrame waits until the frame of the window, as reported by the X server, has the expected geometry.
+func (h *harness) checkFrame(w xproto.Window, want xrect.Rect) error {
+	if want == nil {
+		return fmt.Errorf("no expected geometry for window %d", w)
+	}
+
+	var got xrect.Rect
+	err := waitFor(func() bool {
+		var err error
+		got, err = xwindow.New(h.X, w).DecorGeometry()
+		return err == nil && sameRect(got, want)
+	})
+
+	if err != nil {
+		return fmt.Errorf("window %d is at %v, want %v", w, got, want)
+	}
+	return nil
+}
+
+func sameRect(a, b xrect.Rect) bool {
+	return a.X() == b.X() && a.Y() == b.Y() && a.Width() == b.Width() && a.Height() == b.Height()
+}
+
+// checkVertical checks that the clients of the workspace are tiled in the vertical layout:
+// the masters in a column on the left and the slaves in a column on the right.
+func (h *harness) checkVertical(num uint) error {
+	ws, err := h.workspace(num)
+	if err != nil {
+		return err
+	}
+
+	if !ws.IsTiling || len(ws.Monitors) == 0 {
+		return fmt.Errorf("workspace %d is not tiling", num)
+	}
+
+	m := ws.Monitors[0]
+	if m.Layout != "vertical" {
+		return fmt.Errorf("layout is %s, want vertical", m.Layout)
+	}
+
+	areas, err := ewmh.WorkareaGet(h.X)
+	if err != nil || int(num) >= len(areas) {
+		return errors.New("can't get the work area")
+	}
+
+	a := areas[num]
+	wx, wy, ww, wh := a.X, a.Y, int(a.Width), int(a.Height)
+	gap := testGap
+
+	mw := int(float64(ww) * m.Proportion)
+	if len(m.Slaves) == 0 {
+		mw = ww
+	}
+
+	sx, sw := wx+mw, ww-mw-gap
+	if len(m.Masters) == 0 {
+		sx, sw = wx, ww-gap
+	}
+
+	want := column(wx+gap, wy+gap, mw-2*gap, wh-2*gap, gap, len(m.Masters))
+	want = append(want, column(sx, wy+gap, sw, wh-2*gap, gap, len(m.Slaves))...)
+
+	for i, c := range append(m.Masters, m.Slaves...) {
+		if err := h.checkFrame(xproto.Window(c.Id), want[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// column returns the cells of n windows stacked in the given area.
+func column(x, y, width, height, gap, n int) []xrect.Rect {
+	cells := make([]xrect.Rect, n)
+	for i := range cells {
+		h := (height - (n-1)*gap) / n
+		cells[i] = xrect.New(x, y, width, h)
+		y += h + gap
+	}
+	return cells
+}
@@ -0,0 +1,296 @@
+// Package ipc implements the unix socket protocol used to control a running zentile instance.
+//
+// A client sends a single JSON encoded Request terminated by a newline
+// and receives a single JSON encoded Response terminated by a newline.
+// After a subscribe request, the connection is kept open and
+// every Event is sent as a line of JSON.
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Subscribe is the action that turns a connection into an event stream.
+// Its arguments are the event types to receive, all events are sent if there are none.
+const Subscribe = "subscribe"
+
+// Request is an action, as named in the keybindings config, along with its arguments.
+type Request struct {
+	Action string   `json:"action"`
+	Args   []string `json:"args,omitempty"`
+}
+
+// Response is the result of running a Request.
+type Response struct {
+	Success bool        `json:"success"`
+	Error   string      `json:"error,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Command is a Request received by the server, that is waiting for a Response.
+type Command struct {
+	Request
+	reply chan Response
+}
+
+// Reply hands the response over to the connection of the command.
+// It never blocks, the response is written by the goroutine serving the connection.
+func (c *Command) Reply(r Response) {
+	replies.Add(1)
+	c.reply <- r
+}
+
+// writeTimeout bounds the time spent writing a response to a client that doesn't read it.
+const writeTimeout = 5 * time.Second
+
+// replies counts the responses that were handed over, but not written yet.
+var replies sync.WaitGroup
+
+// Flush waits until the pending responses have been written.
+func Flush() {
+	replies.Wait()
+}
+
+// SocketPath returns the location of the unix socket.
+func SocketPath() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return filepath.Join(os.TempDir(), fmt.Sprintf("zentile-%d.sock", os.Getuid()))
+	}
+
+	return filepath.Join(dir, "zentile.sock")
+}
+
+var listener net.Listener
+
+// Listen starts accepting connections on the socket.
+// Received commands are delivered on the returned channel, so that they
+// can be run from the same goroutine as the X event handlers.
+func Listen() (<-chan *Command, error) {
+	path := SocketPath()
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, errors.New("another instance is listening on " + path)
+	}
+	os.Remove(path)
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	os.Chmod(path, 0600)
+	listener = l
+
+	commands := make(chan *Command)
+	go accept(l, commands)
+	return commands, nil
+}
+
+// Close stops listening and removes the socket, after writing the pending responses.
+func Close() {
+	if listener != nil {
+		listener.Close()
+	}
+	Flush()
+}
+
+func accept(l net.Listener, commands chan<- *Command) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go serve(conn, commands)
+	}
+}
+
+func serve(conn net.Conn, commands chan<- *Command) {
+	defer conn.Close()
+
+	var req Request
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &req)
+	}
+
+	if err != nil {
+		json.NewEncoder(conn).Encode(Response{Error: "invalid request: " + err.Error()})
+	} else if req.Action == Subscribe {
+		subscribe(conn, req.Args)
+	} else {
+		cmd := &Command{Request: req, reply: make(chan Response, 1)}
+		commands <- cmd
+
+		r := <-cmd.reply
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		json.NewEncoder(conn).Encode(r)
+		replies.Done()
+	}
+}
+
+// Send connects to the running instance, sends the request and returns its response.
+func Send(req Request) (Response, error) {
+	var resp Response
+	conn, err := net.Dial("unix", SocketPath())
+	if err != nil {
+		return resp, err
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, err
+	}
+
+	err = json.NewDecoder(conn).Decode(&resp)
+	return resp, err
+}
+
+// Event notifies subscribers about a change in the state of zentile.
+type Event struct {
+	Type      string `json:"event"`
+	Change    string `json:"change,omitempty"`
+	Workspace uint   `json:"workspace"`
+	Head      uint   `json:"head"`
+	IsTiling  bool   `json:"is_tiling"`
+	Layout    string `json:"layout,omitempty"`
+	Masters   int    `json:"masters,omitempty"`
+	Window    uint32 `json:"window,omitempty"`
+}
+
+type subscriber struct {
+	types  []string
+	events chan []byte
+}
+
+func (s *subscriber) wants(eventType string) bool {
+	if len(s.types) == 0 {
+		return true
+	}
+
+	for _, t := range s.types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   = make(map[*subscriber]bool)
+)
+
+// HasSubscribers returns true if any client is subscribed to events.
+func HasSubscribers() bool {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	return len(subscribers) > 0
+}
+
+// Publish sends the event to every subscriber that is interested in it.
+// It never blocks, events are dropped for subscribers that don't keep up.
+func Publish(e Event) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	if len(subscribers) == 0 {
+		return
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		log.Warn("Error encoding event: ", err)
+		return
+	}
+	line = append(line, '\n')
+
+	for s := range subscribers {
+		if !s.wants(e.Type) {
+			continue
+		}
+
+		select {
+		case s.events <- line:
+		default:
+			log.Info("Dropping ", e.Type, " event for a slow subscriber")
+		}
+	}
+}
+
+// subscribe streams events to the connection until it is closed.
+func subscribe(conn net.Conn, types []string) {
+	s := &subscriber{types: types, events: make(chan []byte, 64)}
+
+	subscribersMu.Lock()
+	subscribers[s] = true
+	subscribersMu.Unlock()
+
+	defer func() {
+		subscribersMu.Lock()
+		delete(subscribers, s)
+		subscribersMu.Unlock()
+	}()
+
+	if err := json.NewEncoder(conn).Encode(Response{Success: true}); err != nil {
+		return
+	}
+
+	// Subscribers don't send anything else, reading only detects a closed connection.
+	closed := make(chan struct{})
+	go func() {
+		bufio.NewReader(conn).ReadByte()
+		close(closed)
+	}()
+
+	for {
+		select {
+		case line := <-s.events:
+			if _, err := conn.Write(line); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// Stream subscribes to the events of the running instance
+// and copies them to w until the connection is closed.
+func Stream(types []string, w io.Writer) error {
+	conn, err := net.Dial("unix", SocketPath())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(Request{Action: Subscribe, Args: types}); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(conn)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New(resp.Error)
+	}
+
+	_, err = io.Copy(w, r)
+	return err
+}
@@ -11,11 +11,15 @@ import (
 type keyMapper struct{}
 
 func (k keyMapper) bind(action string, f func()) {
+	keySeq, ok := Config.Keybindings[action]
+	if !ok {
+		return
+	}
+
 	err := keybind.KeyPressFun(
 		func(X *xgbutil.XUtil, ev xevent.KeyPressEvent) {
 			f()
-		}).Connect(state.X, state.X.RootWin(),
-		Config.Keybindings[action], true)
+		}).Connect(state.X, state.X.RootWin(), keySeq, true)
 
 	if err != nil {
 		log.Warn(err)
@@ -23,54 +27,20 @@ func (k keyMapper) bind(action string, f func()) {
 }
 
 func bindKeys(t *tracker) {
-	workspaces := t.workspaces
-	keybind.Initialize(state.X)
 	k := keyMapper{}
 
-	k.bind("tile", func() {
-		ws := workspaces[state.CurrentDesk]
-		ws.IsTiling = true
-		ws.Tile()
-	})
-	k.bind("untile", func() {
-		ws := workspaces[state.CurrentDesk]
-		ws.Untile()
-	})
-	k.bind("make_active_window_master", func() {
-		c := t.clients[state.ActiveWin]
-		ws := workspaces[state.CurrentDesk]
-		ws.ActiveLayout().MakeMaster(c)
-		ws.Tile()
-	})
-	k.bind("switch_layout", func() {
-		workspaces[state.CurrentDesk].SwitchLayout()
-	})
-	k.bind("increase_master", func() {
-		ws := workspaces[state.CurrentDesk]
-		ws.ActiveLayout().IncMaster()
-		ws.Tile()
-	})
-	k.bind("decrease_master", func() {
-		ws := workspaces[state.CurrentDesk]
-		ws.ActiveLayout().DecreaseMaster()
-		ws.Tile()
-	})
-	k.bind("next_window", func() {
-		ws := workspaces[state.CurrentDesk]
-		ws.ActiveLayout().NextClient()
-	})
-	k.bind("previous_window", func() {
-		ws := workspaces[state.CurrentDesk]
-		ws.ActiveLayout().PreviousClient()
-	})
-	k.bind("increment_master", func() {
-		ws := workspaces[state.CurrentDesk]
-		ws.ActiveLayout().IncrementMaster()
-		ws.Tile()
-	})
-	k.bind("decrement_master", func() {
-		ws := workspaces[state.CurrentDesk]
-		ws.ActiveLayout().DecrementMaster()
-		ws.Tile()
-	})
+	for name, a := range actions {
+		a := a
+		k.bind(name, func() {
+			if ws, ok := t.workspaces[state.CurrentDesk]; ok {
+				a(t, ws)
+			}
+		})
+	}
+}
+
+// rebindKeys replaces the keybindings with the ones in the current config.
+func rebindKeys(t *tracker) {
+	keybind.Detach(state.X, state.X.RootWin())
+	bindKeys(t)
 }
@@ -1,11 +1,16 @@
 package main
 
+import (
+	"github.com/BurntSushi/xgbutil/xrect"
+)
+
 const (
 	MASTER_MAX_PROPORTION = 0.9
 	MASTER_MIN_PROPORTION = 0.1
 )
 
 type Layout interface {
+	Name() string
 	Do()
 	Undo()
 	Add(c Client)
@@ -18,12 +23,16 @@ type Layout interface {
 	IncrementMaster()
 	DecrementMaster()
 	sto() *Store
+
+	// geometry returns where the masters and then the slaves are placed.
+	geometry(a arrangement) []xrect.Rect
 }
 
 type VertHorz struct {
 	*Store
 	Proportion   float64
 	WorkspaceNum uint
+	HeadNum      uint
 }
 
 func (l *VertHorz) Undo() {
@@ -58,6 +67,16 @@ func (l *VertHorz) DecrementMaster() {
 	l.Proportion = value
 }
 
+func (l *VertHorz) proportion() float64 {
+	return l.Proportion
+}
+
+func (l *VertHorz) setProportion(p float64) {
+	if p > MASTER_MIN_PROPORTION && p < MASTER_MAX_PROPORTION {
+		l.Proportion = p
+	}
+}
+
 func (l *VertHorz) sto() *Store {
 	return l.Store
 }
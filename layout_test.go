@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/xgbutil/xrect"
+)
+
+func TestLayoutsMoveClients(t *testing.T) {
+	for _, hideDecor := range []bool{false, true} {
+		for i := range createLayouts(0, 0) {
+			fb, tr := newTestTracker(xrect.New(0, 0, 1200, 900))
+			Config.Gap = 10
+			Config.HideDecor = hideDecor
+			for n := 0; n < 4; n++ {
+				fb.AddWindow(fakeWindow{width: 300, height: 200, decorated: true})
+			}
+
+			ws := tr.workspaces[0]
+			ws.IsTiling = true
+			l := ws.monitor(0).layouts[i]
+			l.Do()
+
+			// Fullscreen clients keep their decorations.
+			undecorated := hideDecor && l.Name() != "fullscreen"
+			st := l.sto()
+			want := l.geometry(arrange(st, 0, 0))
+			for n, c := range st.All() {
+				if got := frame(t, fb, c.window); !sameRect(got, want[n]) {
+					t.Errorf("%s, remove_decorations %v: client %d is at %v, want %v", l.Name(), hideDecor, n, got, want[n])
+				}
+				if fb.windows[c.window].decorated == undecorated {
+					t.Errorf("%s, remove_decorations %v: client %d has the wrong decorations", l.Name(), hideDecor, n)
+				}
+			}
+		}
+	}
+}
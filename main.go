@@ -2,26 +2,98 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/BurntSushi/xgbutil/keybind"
 	"github.com/BurntSushi/xgbutil/xevent"
+	"github.com/blrsn/zentile/ipc"
 	"github.com/blrsn/zentile/state"
 	log "github.com/sirupsen/logrus"
 )
 
 func main() {
 	setLogLevel()
+	switch flag.Arg(0) {
+	case "msg":
+		os.Exit(sendMessage(flag.Args()[1:]))
+	case "check-config":
+		os.Exit(checkConfigCommand())
+	}
+
 	state.Populate()
+	keybind.Initialize(state.X)
+	backend = newXBackend(state.X)
+	if err := loadConfig(); err != nil {
+		log.Fatal("Invalid config:\n", err)
+	}
 
 	t := initTracker(CreateWorkspaces())
 	bindKeys(t)
 
+	commands, err := ipc.Listen()
+	if err != nil {
+		log.Warn("Error starting IPC server: ", err)
+	}
+	defer ipc.Close()
+
 	// Run X event loop
-	xevent.Main(state.X)
+	runEventLoop(t, commands)
+}
+
+// quit, restart and reload are signalled by the actions of the same name.
+var (
+	quit    = make(chan struct{}, 1)
+	restart = make(chan struct{}, 1)
+	reload  = make(chan struct{}, 1)
+)
+
+// runEventLoop runs the X event loop along with the IPC commands,
+// so that commands never run concurrently with the X event handlers.
+// The state is saved after every event and command that changed it.
+func runEventLoop(t *tracker, commands <-chan *ipc.Command) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	configChanges := watchConfig()
+
+	pingBefore, pingAfter, pingQuit := xevent.MainPing(state.X)
+	for {
+		select {
+		case <-pingBefore:
+			// Wait for the event handlers to finish.
+			<-pingAfter
+		case cmd := <-commands:
+			cmd.Reply(t.runCommand(cmd.Request))
+		case sig := <-signals:
+			log.Info("Received ", sig, ", shutting down")
+			t.shutdown()
+			return
+		case <-quit:
+			t.shutdown()
+			return
+		case <-restart:
+			t.restart()
+		case <-configChanges:
+			t.reloadConfig()
+		case <-reload:
+			t.reloadConfig()
+		case <-pingQuit:
+			return
+		}
+
+		t.saveState()
+	}
 }
 
 func setLogLevel() {
 	var verbose bool
 	flag.BoolVar(&verbose, "v", false, "verbose mode")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: zentile [-v]\n       zentile msg <action|query> [args...]\n       zentile check-config")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
 	if verbose {
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/blrsn/zentile/ipc"
+)
+
+// Exit statuses of the msg subcommand.
+const (
+	msgOK = iota
+	msgFailed
+	msgError
+)
+
+// sendMessage sends an action or query to the running instance and prints its reply.
+// It returns the exit status of the msg subcommand.
+func sendMessage(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: zentile msg <action|query> [args...]")
+		return msgError
+	}
+
+	if args[0] == ipc.Subscribe {
+		if err := ipc.Stream(args[1:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error subscribing to events:", err)
+			return msgError
+		}
+		return msgOK
+	}
+
+	resp, err := ipc.Send(ipc.Request{Action: args[0], Args: args[1:]})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error connecting to zentile:", err)
+		return msgError
+	}
+
+	out, _ := json.MarshalIndent(resp, "", "  ")
+	fmt.Println(string(out))
+
+	if !resp.Success {
+		return msgFailed
+	}
+	return msgOK
+}
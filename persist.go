@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+)
+
+// savedWorkspace is the state of a workspace, as written to the state file.
+type savedWorkspace struct {
+	IsTiling bool           `json:"is_tiling"`
+	Monitors []savedMonitor `json:"monitors"`
+}
+
+type savedMonitor struct {
+	ActiveLayout uint          `json:"active_layout"`
+	Layouts      []savedLayout `json:"layouts"`
+}
+
+type savedLayout struct {
+	Proportion     float64       `json:"proportion,omitempty"`
+	AllowedMasters int           `json:"allowed_masters"`
+	Masters        []savedClient `json:"masters"`
+	Slaves         []savedClient `json:"slaves"`
+}
+
+// savedClient identifies a window. The class guards against window ids being reused.
+type savedClient struct {
+	Id    uint32 `json:"id"`
+	Class string `json:"class"`
+}
+
+func stateFolderPath() string {
+	xdgStateHome := os.Getenv("XDG_STATE_HOME")
+	if xdgStateHome != "" {
+		return filepath.Join(xdgStateHome, "zentile")
+	}
+
+	stateFolder, _ := homedir.Expand("~/.local/state/zentile/")
+	return stateFolder
+}
+
+func stateFilePath() string {
+	return filepath.Join(stateFolderPath(), "state.json")
+}
+
+// lastSavedState is the content of the state file, used to skip writes when nothing changed.
+var lastSavedState []byte
+
+// saveState writes the state of the workspaces to the state file, if it changed.
+func (t *tracker) saveState() {
+	saved := make(map[uint]savedWorkspace, len(t.workspaces))
+	for num, ws := range t.workspaces {
+		saved[num] = ws.save()
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil || bytes.Equal(data, lastSavedState) {
+		return
+	}
+
+	if err := writeFileAtomic(stateFolderPath(), stateFilePath(), data); err != nil {
+		log.Warn("Error saving state: ", err)
+		return
+	}
+	lastSavedState = data
+}
+
+// writeFileAtomic replaces the file, so that it is never left half written.
+func writeFileAtomic(folder, path string, data []byte) error {
+	if err := os.MkdirAll(folder, 0700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (ws *Workspace) save() savedWorkspace {
+	sw := savedWorkspace{IsTiling: ws.IsTiling}
+	for _, m := range ws.monitors {
+		sm := savedMonitor{ActiveLayout: m.activeLayoutNum}
+		for _, l := range m.layouts {
+			st := l.sto()
+			sl := savedLayout{
+				AllowedMasters: st.allowedMasters,
+				Masters:        saveClients(st.masters),
+				Slaves:         saveClients(st.slaves),
+			}
+
+			if p, ok := l.(interface{ proportion() float64 }); ok {
+				sl.Proportion = p.proportion()
+			}
+			sm.Layouts = append(sm.Layouts, sl)
+		}
+		sw.Monitors = append(sw.Monitors, sm)
+	}
+
+	return sw
+}
+
+func saveClients(clients []Client) []savedClient {
+	saved := make([]savedClient, len(clients))
+	for i, c := range clients {
+		saved[i] = savedClient{uint32(c.window), c.class}
+	}
+	return saved
+}
+
+// restoreState applies the state file to the workspaces and retiles them.
+func (t *tracker) restoreState() {
+	data, err := ioutil.ReadFile(stateFilePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("Error reading state: ", err)
+		}
+		return
+	}
+
+	var saved map[uint]savedWorkspace
+	if err := json.Unmarshal(data, &saved); err != nil {
+		log.Warn("Error reading state: ", err)
+		return
+	}
+
+	t.restoreWorkspaces(saved)
+	lastSavedState = data
+}
+
+func (t *tracker) restoreWorkspaces(saved map[uint]savedWorkspace) {
+	for num, sw := range saved {
+		if ws, ok := t.workspaces[num]; ok {
+			ws.restore(sw, t.clients)
+			ws.Tile()
+		}
+	}
+}
+
+func (ws *Workspace) restore(sw savedWorkspace, clients map[xproto.Window]*Client) {
+	ws.IsTiling = sw.IsTiling
+	for h, sm := range sw.Monitors {
+		if h >= len(ws.monitors) {
+			break
+		}
+
+		m := ws.monitors[h]
+		if sm.ActiveLayout < uint(len(m.layouts)) {
+			m.activeLayoutNum = sm.ActiveLayout
+		}
+
+		for i, sl := range sm.Layouts {
+			if i >= len(m.layouts) {
+				break
+			}
+
+			l := m.layouts[i]
+			if p, ok := l.(interface{ setProportion(float64) }); ok && sl.Proportion > 0 {
+				p.setProportion(sl.Proportion)
+			}
+			l.sto().restore(sl, clients)
+		}
+	}
+}
+
+// restore reorders the clients of the store as saved.
+// Clients that were not saved keep their order after the saved ones.
+func (st *Store) restore(sl savedLayout, clients map[xproto.Window]*Client) {
+	pool := make(map[xproto.Window]bool)
+	for _, c := range st.All() {
+		pool[c.window] = true
+	}
+
+	ordered := make([]Client, 0, len(pool))
+	for _, sc := range append(sl.Masters, sl.Slaves...) {
+		w := xproto.Window(sc.Id)
+		if c, ok := clients[w]; ok && pool[w] && c.class == sc.Class {
+			ordered = append(ordered, *c)
+			delete(pool, w)
+		}
+	}
+
+	for _, c := range st.All() {
+		if pool[c.window] {
+			ordered = append(ordered, c)
+		}
+	}
+
+	if sl.AllowedMasters > 0 {
+		st.allowedMasters = sl.AllowedMasters
+	}
+
+	k := st.allowedMasters
+	if k > len(ordered) {
+		k = len(ordered)
+	}
+	st.masters = ordered[:k:k]
+	st.slaves = ordered[k:]
+}
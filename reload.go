@@ -0,0 +1,90 @@
+package main
+
+import (
+	"github.com/BurntSushi/xgb/xproto"
+	log "github.com/sirupsen/logrus"
+)
+
+// reloadConfig reads the config file again and applies it.
+// The current config stays in effect if the file is invalid.
+func (t *tracker) reloadConfig() {
+	old := Config
+	if err := loadConfig(); err != nil {
+		log.Error("Invalid config, keeping the current one: ", err)
+		return
+	}
+	log.Info("Reloaded config")
+
+	rebindKeys(t)
+	t.reapplyRules()
+
+	// Windows that were ignored until now are picked up.
+	t.ignored = make(map[xproto.Window]bool)
+	t.populateClients()
+
+	for _, ws := range t.workspaces {
+		if old.HideDecor && !Config.HideDecor && ws.IsTiling {
+			for _, c := range t.clients {
+				if c.Desk == ws.num && !c.floating {
+					c.Decorate()
+				}
+			}
+		}
+		ws.Tile()
+	}
+}
+
+// reapplyRules applies the rules to the tracked clients.
+// Only the explicit floating of rules is applied, so that windows floated by hand stay floating.
+func (t *tracker) reapplyRules() {
+	for w, c := range t.clients {
+		r := matchRules(w)
+		ws := t.workspaces[c.Desk]
+
+		if r.Ignore {
+			if ws.IsTiling && !c.floating {
+				c.Restore()
+			}
+			t.unTrack(w)
+			continue
+		}
+
+		if r.NoDecorations {
+			c.savedProp.decoration = false
+			c.UnDecorate()
+		}
+
+		floating := c.floating
+		if r.Float != nil {
+			floating = *r.Float
+		}
+		if r.Width > 0 && r.Height > 0 {
+			floating = true
+		}
+
+		p := placeAuto
+		if r.Master {
+			p = placeMaster
+		} else if r.Stack {
+			p = placeStack
+		}
+
+		if floating == c.floating && p == c.placement {
+			continue
+		}
+
+		// Clients are re-added, since the layouts hold copies of them.
+		ws.RemoveClient(*c)
+		if floating && !c.floating && ws.IsTiling {
+			c.Restore()
+		}
+		if floating != c.floating {
+			c.floatedBySticky = false
+		}
+		c.floating, c.placement = floating, p
+		if geom, err := backend.Geometry(c.window); err == nil {
+			c.Head = backend.HeadForRect(geom)
+		}
+		ws.AddClient(*c)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil/xrect"
+	"github.com/blrsn/zentile/ipc"
+	log "github.com/sirupsen/logrus"
+)
+
+// restartEnv holds the path of the restart file in the environment of the restarted process.
+const restartEnv = "ZENTILE_RESTART_STATE"
+
+// restartState is everything needed to pick up where the previous process stopped.
+type restartState struct {
+	Workspaces map[uint]savedWorkspace `json:"workspaces"`
+	Clients    []savedClientProp       `json:"clients"`
+}
+
+// savedClientProp holds the properties of a client that can't be read back from the X server.
+type savedClientProp struct {
+	savedClient
+	X          int       `json:"x"`
+	Y          int       `json:"y"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	Decoration bool      `json:"decoration"`
+	Floating   bool      `json:"floating"`
+	Placement  placement `json:"placement"`
+}
+
+func restartFilePath() string {
+	return filepath.Join(stateFolderPath(), "restart.json")
+}
+
+// restart saves the full state and replaces the process with a new instance of zentile,
+// which restores it. Windows are left in place.
+func (t *tracker) restart() {
+	rs := restartState{Workspaces: make(map[uint]savedWorkspace, len(t.workspaces))}
+	for num, ws := range t.workspaces {
+		rs.Workspaces[num] = ws.save()
+	}
+
+	for _, c := range t.clients {
+		x, y, w, h := xrect.Pieces(c.savedProp.Geom)
+		rs.Clients = append(rs.Clients, savedClientProp{
+			savedClient: savedClient{uint32(c.window), c.class},
+			X:           x,
+			Y:           y,
+			Width:       w,
+			Height:      h,
+			Decoration:  c.savedProp.decoration,
+			Floating:    c.floating,
+			Placement:   c.placement,
+		})
+	}
+
+	data, err := json.Marshal(rs)
+	if err == nil {
+		err = writeFileAtomic(stateFolderPath(), restartFilePath(), data)
+	}
+	if err != nil {
+		log.Warn("Error saving state for restart: ", err)
+		return
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		log.Warn("Error restarting: ", err)
+		return
+	}
+
+	env := []string{restartEnv + "=" + restartFilePath()}
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, restartEnv+"=") {
+			env = append(env, e)
+		}
+	}
+
+	// The IPC socket is closed on exec, the new process takes over its path.
+	ipc.Flush()
+	backend.Sync()
+	err = syscall.Exec(exe, os.Args, env)
+
+	// Exec only returns on failure, keep running with the current state.
+	log.Warn("Error restarting: ", err)
+}
+
+// restoreRestartState restores the state saved by restart, if zentile was restarted.
+// It returns false if there is no such state.
+func (t *tracker) restoreRestartState() bool {
+	path := os.Getenv(restartEnv)
+	if path == "" {
+		return false
+	}
+	os.Unsetenv(restartEnv)
+
+	data, err := ioutil.ReadFile(path)
+	os.Remove(path)
+	if err != nil {
+		log.Warn("Error reading restart state: ", err)
+		return false
+	}
+
+	var rs restartState
+	if err := json.Unmarshal(data, &rs); err != nil {
+		log.Warn("Error reading restart state: ", err)
+		return false
+	}
+
+	for _, sc := range rs.Clients {
+		c, ok := t.clients[xproto.Window(sc.Id)]
+		if !ok || c.class != sc.Class {
+			continue
+		}
+
+		ws := t.workspaces[c.Desk]
+		ws.RemoveClient(*c)
+		c.savedProp = Prop{
+			Geom:       xrect.New(sc.X, sc.Y, sc.Width, sc.Height),
+			decoration: sc.Decoration,
+		}
+		c.floating = sc.Floating
+		c.placement = sc.Placement
+		ws.AddClient(*c)
+	}
+
+	t.restoreWorkspaces(rs.Workspaces)
+	return true
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/blrsn/zentile/state"
+)
+
+// Rule applies its actions to the windows that match all of its non-empty criteria.
+type Rule struct {
+	// Criteria
+	Class    string // Class part of WM_CLASS, compared case insensitively.
+	Instance string // Instance part of WM_CLASS, compared case insensitively.
+	Title    string // Regular expression matched against _NET_WM_NAME.
+	Role     string // WM_WINDOW_ROLE
+	Type     string // _NET_WM_WINDOW_TYPE, with or without the _NET_WM_WINDOW_TYPE_ prefix.
+
+	// Actions
+	Ignore        bool  // Don't track the window at all.
+	Float         *bool // Track the window, but keep it out of the tiling. Overrides the default for dialogs.
+	Workspace     *uint
+	Master        bool // Always add the window as master.
+	Stack         bool // Never make the window a master, unless asked for.
+	Width, Height int  // Float the window with a fixed size.
+	NoDecorations bool `toml:"no_decorations"`
+
+	title *regexp.Regexp
+}
+
+// compileRules prepares the rules in the config, converting the legacy ignore list into rules.
+func (c *cfg) compileRules() error {
+	for _, class := range c.WindowsToIgnore {
+		c.Rules = append(c.Rules, Rule{Class: class, Ignore: true})
+	}
+
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.Title == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(r.Title)
+		if err != nil {
+			return fmt.Errorf("invalid title in rule %d: %v", i+1, err)
+		}
+		r.title = re
+	}
+
+	return nil
+}
+
+// windowInfo holds the window properties that rules are matched against.
+type windowInfo struct {
+	class, instance, title, role string
+	types                        []string
+}
+
+func getWindowInfo(w xproto.Window) windowInfo {
+	var info windowInfo
+	info.class, info.instance, _ = backend.Class(w)
+	info.title = backend.Name(w)
+	info.role = backend.Role(w)
+	info.types = backend.Types(w)
+	return info
+}
+
+func (r Rule) matches(info windowInfo) bool {
+	if r.Class != "" && !strings.EqualFold(r.Class, info.class) {
+		return false
+	}
+
+	if r.Instance != "" && !strings.EqualFold(r.Instance, info.instance) {
+		return false
+	}
+
+	if r.title != nil && !r.title.MatchString(info.title) {
+		return false
+	}
+
+	if r.Role != "" && r.Role != info.role {
+		return false
+	}
+
+	if r.Type != "" && !hasWindowType(info.types, r.Type) {
+		return false
+	}
+
+	return true
+}
+
+// hasWindowType returns true if any of the types equals name, e.g. "dialog" or "_NET_WM_WINDOW_TYPE_DIALOG".
+func hasWindowType(types []string, name string) bool {
+	name = strings.TrimPrefix(strings.ToUpper(name), "_NET_WM_WINDOW_TYPE_")
+	for _, t := range types {
+		if strings.TrimPrefix(t, "_NET_WM_WINDOW_TYPE_") == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchRules merges the actions of every rule that matches the window.
+// Rules later in the config take precedence.
+func matchRules(w xproto.Window) Rule {
+	var merged Rule
+	if len(Config.Rules) == 0 {
+		return merged
+	}
+
+	info := getWindowInfo(w)
+	for _, r := range Config.Rules {
+		if !r.matches(info) {
+			continue
+		}
+
+		merged.Ignore = merged.Ignore || r.Ignore
+		if r.Float != nil {
+			merged.Float = r.Float
+		}
+		merged.NoDecorations = merged.NoDecorations || r.NoDecorations
+		if r.Workspace != nil {
+			merged.Workspace = r.Workspace
+		}
+		if r.Master || r.Stack {
+			merged.Master, merged.Stack = r.Master, r.Stack
+		}
+		if r.Width > 0 && r.Height > 0 {
+			merged.Width, merged.Height = r.Width, r.Height
+		}
+	}
+
+	return merged
+}
+
+// applyRule applies the actions of a rule to a newly tracked client.
+// Dialogs and transient windows are floated, unless the rule says otherwise.
+func applyRule(c *Client, r Rule) {
+	if r.Workspace != nil && *r.Workspace != c.Desk && *r.Workspace < state.DeskCount {
+		backend.SetDesktop(c.window, *r.Workspace)
+		c.Desk = *r.Workspace
+	}
+
+	if r.Master {
+		c.placement = placeMaster
+	} else if r.Stack {
+		c.placement = placeStack
+	}
+
+	if r.NoDecorations {
+		c.savedProp.decoration = false
+		c.UnDecorate()
+	}
+
+	if r.Float != nil {
+		c.floating = *r.Float
+		c.floatedBySticky = false
+	} else if shouldFloat(c.window) {
+		c.floating = true
+		c.floatedBySticky = false
+		c.CenterOnParent()
+	}
+
+	if r.Width > 0 && r.Height > 0 {
+		c.floating = true
+		c.floatedBySticky = false
+		dw, dh := c.DecorDimensions()
+		backend.Resize(c.window, r.Width-dw, r.Height-dh)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/BurntSushi/xgbutil/xrect"
+	log "github.com/sirupsen/logrus"
+)
+
+// SpiralLayout gives each client half of the area left over by the previous one,
+// turning clockwise, like dwm's fibonacci layout.
+// The first split is sized by the master proportion.
+type SpiralLayout struct {
+	*VertHorz
+}
+
+func (l *SpiralLayout) Name() string {
+	return "spiral"
+}
+
+func (l *SpiralLayout) Do() {
+	log.Info("Switching to Spiral layout")
+	prepareTiling(l.All())
+	applyLayout(l, l.WorkspaceNum, l.HeadNum)
+}
+
+func (l *SpiralLayout) geometry(a arrangement) []xrect.Rect {
+	tiles := a.all()
+	csize := len(tiles)
+	gap := a.gap
+
+	wx, wy, ww, wh := a.area.Pieces()
+	x, y, w, h := wx+gap, wy+gap, ww-2*gap, wh-2*gap
+
+	rects := make([]xrect.Rect, csize)
+	for i, t := range tiles {
+		cx, cy := x, y
+		cw, ch := w, h
+
+		// The size of each client is fitted to its size hints before splitting,
+		// so the remaining area gets the space it can't use.
+		if i == csize-1 {
+			cw, ch = t.fit(w, h)
+		} else {
+			proportion := 0.5
+			if i == 0 {
+				proportion = l.Proportion
+			}
+
+			switch i % 4 {
+			case 0: // left, remaining area to the right
+				cw, ch = t.fit(int(float64(w-gap)*proportion), h)
+				x, w = x+cw+gap, w-cw-gap
+			case 1: // top, remaining area below
+				cw, ch = t.fit(w, int(float64(h-gap)*proportion))
+				y, h = y+ch+gap, h-ch-gap
+			case 2: // right, remaining area to the left
+				cw, ch = t.fit(int(float64(w-gap)*proportion), h)
+				cx = x + w - cw
+				w = w - cw - gap
+			case 3: // bottom, remaining area above
+				cw, ch = t.fit(w, int(float64(h-gap)*proportion))
+				cy = y + h - ch
+				h = h - ch - gap
+			}
+		}
+
+		rects[i] = xrect.New(cx, cy, cw, ch)
+	}
+
+	return rects
+}
@@ -0,0 +1,224 @@
+package state
+
+import (
+	"sort"
+
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil"
+	"github.com/BurntSushi/xgbutil/ewmh"
+	"github.com/BurntSushi/xgbutil/xevent"
+	"github.com/BurntSushi/xgbutil/xinerama"
+	"github.com/BurntSushi/xgbutil/xrect"
+	"github.com/BurntSushi/xgbutil/xwindow"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	Heads     []xrect.Rect // Geometry of the physical monitors, ordered left to right.
+	headAreas []xrect.Rect // Geometry of the monitors, excluding the space reserved by panels and docks.
+	hasRandr  bool
+
+	geometryChangeFuns []func()
+)
+
+func initHeads() {
+	if err := randr.Init(X.Conn()); err != nil {
+		log.Info("RandR is not available, falling back to Xinerama: ", err)
+	} else {
+		hasRandr = true
+		mask := randr.NotifyMaskScreenChange | randr.NotifyMaskCrtcChange | randr.NotifyMaskOutputChange
+		randr.SelectInput(X.Conn(), X.RootWin(), uint16(mask))
+		xevent.HookFun(randrUpdate).Connect(X)
+	}
+
+	updateHeads()
+}
+
+// OnGeometryChange registers a function that is called
+// whenever the monitor layout or the area reserved by panels changes.
+func OnGeometryChange(f func()) {
+	geometryChangeFuns = append(geometryChangeFuns, f)
+}
+
+func notifyGeometryChange() {
+	for _, f := range geometryChangeFuns {
+		f()
+	}
+}
+
+// randrUpdate refreshes the heads when a monitor is connected, disconnected or reconfigured.
+// RandR events are consumed here, since xevent has no callbacks for them.
+func randrUpdate(X *xgbutil.XUtil, ev interface{}) bool {
+	switch ev.(type) {
+	case randr.ScreenChangeNotifyEvent, randr.NotifyEvent:
+		old := Heads
+		updateHeads()
+		if !sameHeads(old, Heads) {
+			log.Info("Monitor configuration changed: ", Heads)
+			notifyGeometryChange()
+		}
+		return false
+	}
+
+	return true
+}
+
+func sameHeads(a, b []xrect.Rect) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		ax, ay, aw, ah := xrect.Pieces(a[i])
+		bx, by, bw, bh := xrect.Pieces(b[i])
+		if ax != bx || ay != by || aw != bw || ah != bh {
+			return false
+		}
+	}
+	return true
+}
+
+// updateHeads queries the monitor geometry, preferring RandR over Xinerama.
+func updateHeads() {
+	var heads []xrect.Rect
+	if hasRandr {
+		heads = randrHeads()
+	}
+
+	if len(heads) == 0 && X.ExtInitialized("XINERAMA") {
+		if hds, err := xinerama.PhysicalHeads(X); err == nil {
+			heads = hds
+		}
+	}
+
+	if len(heads) == 0 {
+		s := X.Screen()
+		heads = []xrect.Rect{xrect.New(0, 0, int(s.WidthInPixels), int(s.HeightInPixels))}
+	}
+
+	Heads = heads
+	updateHeadAreas()
+}
+
+// randrHeads returns the geometry of every active CRTC, skipping cloned displays.
+func randrHeads() []xrect.Rect {
+	res, err := randr.GetScreenResourcesCurrent(X.Conn(), X.RootWin()).Reply()
+	if err != nil {
+		log.Info("Error querying RandR screen resources: ", err)
+		return nil
+	}
+
+	heads := make(xinerama.Heads, 0, len(res.Crtcs))
+	for _, crtc := range res.Crtcs {
+		info, err := randr.GetCrtcInfo(X.Conn(), crtc, res.ConfigTimestamp).Reply()
+		if err != nil || info.Mode == 0 || info.Width == 0 || info.Height == 0 {
+			continue
+		}
+
+		head := xrect.New(int(info.X), int(info.Y), int(info.Width), int(info.Height))
+		if !containsOrigin(heads, head) {
+			heads = append(heads, head)
+		}
+	}
+
+	sort.Sort(heads)
+	return heads
+}
+
+func containsOrigin(heads []xrect.Rect, r xrect.Rect) bool {
+	for _, h := range heads {
+		if h.X() == r.X() && h.Y() == r.Y() {
+			return true
+		}
+	}
+	return false
+}
+
+// updateHeadAreas shrinks each head by the struts of the windows that reserve space on it,
+// so that a panel on one monitor does not affect the others.
+func updateHeadAreas() {
+	areas := make([]xrect.Rect, len(Heads))
+	for i, h := range Heads {
+		areas[i] = xrect.New(xrect.Pieces(h))
+	}
+
+	s := X.Screen()
+	for _, w := range strutWindows() {
+		st, err := ewmh.WmStrutPartialGet(X, w)
+		if err != nil {
+			// The legacy _NET_WM_STRUT spans the whole edge of the screen, as per EWMH.
+			legacy, err := ewmh.WmStrutGet(X, w)
+			if err != nil {
+				continue
+			}
+
+			st = &ewmh.WmStrutPartial{
+				Left: legacy.Left, Right: legacy.Right, Top: legacy.Top, Bottom: legacy.Bottom,
+				LeftEndY: uint(s.HeightInPixels) - 1, RightEndY: uint(s.HeightInPixels) - 1,
+				TopEndX: uint(s.WidthInPixels) - 1, BottomEndX: uint(s.WidthInPixels) - 1,
+			}
+		}
+
+		xrect.ApplyStrut(areas, uint(s.WidthInPixels), uint(s.HeightInPixels),
+			st.Left, st.Right, st.Top, st.Bottom,
+			st.LeftStartY, st.LeftEndY, st.RightStartY, st.RightEndY,
+			st.TopStartX, st.TopEndX, st.BottomStartX, st.BottomEndX)
+	}
+
+	headAreas = areas
+}
+
+// strutWindows returns the managed clients and top level windows, which may carry struts.
+func strutWindows() []xproto.Window {
+	windows := append([]xproto.Window{}, Stacking...)
+
+	tree, err := xproto.QueryTree(X.Conn(), X.RootWin()).Reply()
+	if err != nil {
+		log.Info("Error querying the window tree: ", err)
+		return windows
+	}
+
+	return append(windows, tree.Children...)
+}
+
+// HeadCount returns the number of monitors.
+func HeadCount() uint {
+	return uint(len(Heads))
+}
+
+// HeadForRect returns the head that contains the center of the rectangle.
+// If none does, the head with the largest overlap is returned.
+func HeadForRect(r xrect.Rect) uint {
+	if r == nil {
+		return 0
+	}
+
+	cx, cy := r.X()+r.Width()/2, r.Y()+r.Height()/2
+	for i, h := range Heads {
+		if cx >= h.X() && cx < h.X()+h.Width() && cy >= h.Y() && cy < h.Y()+h.Height() {
+			return uint(i)
+		}
+	}
+
+	if i := xrect.LargestOverlap(r, Heads); i >= 0 {
+		return uint(i)
+	}
+	return 0
+}
+
+// ActiveHead returns the head of the active window,
+// or the head under the pointer if there is no active window.
+func ActiveHead() uint {
+	if ActiveWin != 0 {
+		if geom, err := xwindow.New(X, ActiveWin).DecorGeometry(); err == nil {
+			return HeadForRect(geom)
+		}
+	}
+
+	p, err := xproto.QueryPointer(X.Conn(), X.RootWin()).Reply()
+	if err != nil {
+		return 0
+	}
+	return HeadForRect(xrect.New(int(p.RootX), int(p.RootY), 1, 1))
+}
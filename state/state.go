@@ -7,6 +7,7 @@ import (
 	"github.com/BurntSushi/xgbutil/ewmh"
 	"github.com/BurntSushi/xgbutil/xevent"
 	"github.com/BurntSushi/xgbutil/xprop"
+	"github.com/BurntSushi/xgbutil/xrect"
 	"github.com/BurntSushi/xgbutil/xwindow"
 	log "github.com/sirupsen/logrus"
 )
@@ -20,6 +21,9 @@ var (
 	workArea    []ewmh.Workarea
 )
 
+// rootUpdates maps the atoms of the root window properties that are kept up-to-date to their update functions.
+var rootUpdates map[xproto.Atom]func() error
+
 // Populate initializes the state variables and registers the callbacks required for keeping them up-to-date.
 func Populate() {
 	var err error
@@ -28,6 +32,8 @@ func Populate() {
 
 	checkEwmhCompliance()
 	populateState()
+	initHeads()
+	internAtoms()
 
 	win := xwindow.New(X, X.RootWin())
 	win.Listen(xproto.EventMaskPropertyChange)
@@ -67,27 +73,63 @@ func checkEwmhCompliance() {
 	}
 }
 
+// internAtoms interns the atoms of the root window properties once,
+// so that property events are dispatched without asking the server for their names.
+func internAtoms() {
+	updates := map[string]func() error{
+		"_NET_ACTIVE_WINDOW": func() (err error) {
+			ActiveWin, err = ewmh.ActiveWindowGet(X)
+			return
+		},
+		"_NET_CURRENT_DESKTOP": func() (err error) {
+			CurrentDesk, err = ewmh.CurrentDesktopGet(X)
+			return
+		},
+		"_NET_NUMBER_OF_DESKTOPS": func() (err error) {
+			DeskCount, err = ewmh.NumberOfDesktopsGet(X)
+			return
+		},
+		"_NET_CLIENT_LIST_STACKING": func() (err error) {
+			Stacking, err = ewmh.ClientListStackingGet(X)
+			return
+		},
+		"_NET_WORKAREA": func() (err error) {
+			workArea, err = ewmh.WorkareaGet(X)
+			updateHeadAreas()
+			notifyGeometryChange()
+			return
+		},
+	}
+
+	rootUpdates = make(map[xproto.Atom]func() error, len(updates))
+	for name, update := range updates {
+		atom, err := xprop.Atm(X, name)
+		checkErr(err)
+		rootUpdates[atom] = update
+	}
+}
+
 func stateUpdate(X *xgbutil.XUtil, e xevent.PropertyNotifyEvent) {
-	var err error
-	if aname, _ := xprop.AtomName(X, e.Atom); aname == "_NET_ACTIVE_WINDOW" {
-		ActiveWin, err = ewmh.ActiveWindowGet(X)
-	} else if aname, _ := xprop.AtomName(X, e.Atom); aname == "_NET_CURRENT_DESKTOP" {
-		CurrentDesk, err = ewmh.CurrentDesktopGet(X)
-	} else if aname, _ := xprop.AtomName(X, e.Atom); aname == "_NET_NUMBER_OF_DESKTOPS" {
-		DeskCount, err = ewmh.NumberOfDesktopsGet(X)
-	} else if aname, _ := xprop.AtomName(X, e.Atom); aname == "_NET_CLIENT_LIST_STACKING" {
-		Stacking, err = ewmh.ClientListStackingGet(X)
-	} else if aname, _ := xprop.AtomName(X, e.Atom); aname == "_NET_WORKAREA" {
-		workArea, err = ewmh.WorkareaGet(X)
+	update, ok := rootUpdates[e.Atom]
+	if !ok {
+		return
 	}
 
-	if err != nil {
+	if err := update(); err != nil {
 		log.Warn("Error updating state: ", err)
 	}
 }
 
-// WorkAreaDimensions returns the dimension of the requested workspace.
-func WorkAreaDimensions(num uint) (x, y, width, height int) {
+// WorkAreaDimensions returns the dimension of the requested head in a workspace.
+// With a single head, the work area reported by the window manager is used.
+func WorkAreaDimensions(num, head uint) (x, y, width, height int) {
+	if len(headAreas) > 1 {
+		if head >= uint(len(headAreas)) {
+			head = uint(len(headAreas)) - 1
+		}
+		return xrect.Pieces(headAreas[head])
+	}
+
 	w := workArea[num]
 	x = w.X
 	y = w.Y
@@ -17,19 +17,45 @@ func buildStore() *Store {
 }
 
 func (st *Store) Add(c Client) {
-	if len(st.masters) < st.allowedMasters {
+	switch {
+	case c.placement == placeMaster:
+		st.masters = append([]Client{c}, st.masters...)
+		if len(st.masters) > st.allowedMasters {
+			mlen := len(st.masters)
+			st.slaves = append([]Client{st.masters[mlen-1]}, st.slaves...)
+			st.masters = st.masters[:mlen-1]
+		}
+	case c.placement == placeStack && len(st.masters) > 0:
+		st.slaves = append(st.slaves, c)
+	case len(st.masters) < st.allowedMasters:
 		st.masters = append(st.masters, c)
-	} else {
+	default:
 		st.slaves = append(st.slaves, c)
 	}
 }
 
+// promotable returns the index of the first slave that may become a master, or -1.
+func (st *Store) promotable() int {
+	for i, s := range st.slaves {
+		if s.placement != placeStack {
+			return i
+		}
+	}
+	return -1
+}
+
 func (st *Store) Remove(c Client) {
 	for i, m := range st.masters {
-		if m.window.Id == c.window.Id {
-			if len(st.slaves) > 0 {
-				st.masters[i] = st.slaves[0]
-				st.slaves = st.slaves[1:]
+		if m.window == c.window {
+			// Stacked slaves only stay out of the masters while there is another master.
+			p := st.promotable()
+			if p < 0 && len(st.masters) == 1 && len(st.slaves) > 0 {
+				p = 0
+			}
+
+			if p >= 0 {
+				st.masters[i] = st.slaves[p]
+				st.slaves = removeElement(st.slaves, p)
 			} else {
 				st.masters = removeElement(st.masters, i)
 			}
@@ -38,7 +64,7 @@ func (st *Store) Remove(c Client) {
 	}
 
 	for i, s := range st.slaves {
-		if s.window.Id == c.window.Id {
+		if s.window == c.window {
 			st.slaves = removeElement(st.slaves, i)
 			return
 		}
@@ -50,10 +76,10 @@ func removeElement(s []Client, i int) []Client {
 }
 
 func (st *Store) IncMaster() {
-	if len(st.slaves) > 1 {
+	if p := st.promotable(); len(st.slaves) > 1 && p >= 0 {
 		st.allowedMasters = st.allowedMasters + 1
-		st.masters = append(st.masters, st.slaves[0])
-		st.slaves = st.slaves[1:]
+		st.masters = append(st.masters, st.slaves[p])
+		st.slaves = removeElement(st.slaves, p)
 	}
 }
 
@@ -68,9 +94,17 @@ func (st *Store) DecreaseMaster() {
 
 func (st *Store) MakeMaster(c Client) {
 	for i, slave := range st.slaves {
-		if slave.window.Id == c.window.Id {
+		if slave.window != c.window {
+			continue
+		}
+
+		if len(st.masters) == 0 {
+			st.masters = append(st.masters, slave)
+			st.slaves = removeElement(st.slaves, i)
+		} else {
 			st.masters[0], st.slaves[i] = st.slaves[i], st.masters[0]
 		}
+		return
 	}
 }
 
@@ -83,7 +117,7 @@ func (st *Store) Next() Client {
 	lastIndex := len(clients) - 1
 
 	for i, c := range clients {
-		if c.window.Id == state.ActiveWin {
+		if c.window == state.ActiveWin {
 			next := i + 1
 			if next > lastIndex {
 				next = 0
@@ -100,7 +134,7 @@ func (st *Store) Previous() Client {
 	lastIndex := len(clients) - 1
 
 	for i, c := range clients {
-		if c.window.Id == state.ActiveWin {
+		if c.window == state.ActiveWin {
 			prev := i - 1
 			if prev < 0 {
 				prev = lastIndex
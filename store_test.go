@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil/xrect"
+	"github.com/blrsn/zentile/state"
+)
+
+func TestRemoveMasterWithStackedSlaves(t *testing.T) {
+	st := buildStore()
+	st.Add(Client{window: 1})
+	st.Add(Client{window: 2, placement: placeStack})
+	st.Add(Client{window: 3, placement: placeStack})
+
+	st.Remove(Client{window: 1})
+	if len(st.masters) != 1 || st.masters[0].window != 2 {
+		t.Fatalf("masters are %v, want the first slave", st.masters)
+	}
+	if len(st.slaves) != 1 || st.slaves[0].window != 3 {
+		t.Errorf("slaves are %v, want the second slave", st.slaves)
+	}
+}
+
+func TestMakeMasterWithoutMasters(t *testing.T) {
+	st := &Store{allowedMasters: 1, slaves: []Client{{window: 1}, {window: 2}}}
+
+	st.MakeMaster(Client{window: 2})
+	if len(st.masters) != 1 || st.masters[0].window != 2 {
+		t.Errorf("masters are %v, want the new master", st.masters)
+	}
+	if len(st.slaves) != 1 || st.slaves[0].window != 1 {
+		t.Errorf("slaves are %v, want the other client", st.slaves)
+	}
+}
+
+func TestMakeStackedClientMaster(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800))
+	Config.Rules = []Rule{{Class: "slack", Stack: true}}
+	plain := fb.AddWindow(fakeWindow{decorated: true})
+	slack := fb.AddWindow(fakeWindow{class: "Slack", decorated: true})
+	ws := tr.workspaces[0]
+	ws.IsTiling = true
+
+	fb.RemoveWindow(plain)
+	state.ActiveWin = slack
+	actions["make_active_window_master"](tr, ws)
+
+	if got := layoutClients(ws, 0); !sameWindows(got, []xproto.Window{slack}) {
+		t.Errorf("workspace has %v, want %v", got, []xproto.Window{slack})
+	}
+	if got, want := frame(t, fb, slack), xrect.New(0, 0, 1000, 800); !sameRect(got, want) {
+		t.Errorf("client is at %v, want %v", got, want)
+	}
+}
@@ -2,53 +2,70 @@ package main
 
 import (
 	"github.com/BurntSushi/xgb/xproto"
-	"github.com/BurntSushi/xgbutil"
-	"github.com/BurntSushi/xgbutil/ewmh"
-	"github.com/BurntSushi/xgbutil/xevent"
-	"github.com/BurntSushi/xgbutil/xprop"
+	"github.com/BurntSushi/xgbutil/xrect"
+	"github.com/blrsn/zentile/ipc"
 	"github.com/blrsn/zentile/state"
 )
 
 type tracker struct {
-	clients    map[xproto.Window]Client // List of clients that are being tracked.
+	clients    map[xproto.Window]*Client // List of clients that are being tracked.
+	ignored    map[xproto.Window]bool    // Windows ignored by the rules, so that they aren't matched again.
 	workspaces map[uint]*Workspace
 }
 
 func initTracker(ws map[uint]*Workspace) *tracker {
 	t := tracker{
-		clients:    make(map[xproto.Window]Client),
+		clients:    make(map[xproto.Window]*Client),
+		ignored:    make(map[xproto.Window]bool),
 		workspaces: ws,
 	}
 
-	xevent.PropertyNotifyFun(t.handleClientUpdates).Connect(state.X, state.X.RootWin())
+	root := make(map[string]func(), len(rootHandlers))
+	for prop, handle := range rootHandlers {
+		handle := handle
+		root[prop] = func() { handle(&t) }
+	}
+	backend.Watch(backend.Root(), root)
+	backend.WatchHeads(t.handleGeometryChange)
 	t.populateClients()
+	if !t.restoreRestartState() {
+		t.restoreState()
+	}
 	return &t
 }
 
 // UpdateClients updates the list of tracked clients with the most up to date list of clients.
 func (tr *tracker) populateClients() {
-	clientList, _ := ewmh.ClientListStackingGet(state.X)
+	clientList := backend.Clients()
 	for _, w := range clientList {
-		if isHidden(w) || shouldIgnore(w) {
+		if tr.IsTracked(w) || tr.ignored[w] || isHidden(w) {
 			continue
 		}
 		tr.trackWindow(w)
 	}
 
+	// Ignored windows that were closed are forgotten, since their ids can be reused.
+	for wid := range tr.ignored {
+		if !contains(clientList, wid) {
+			delete(tr.ignored, wid)
+		}
+	}
+
 	// If window is tracked, but not in client list
 	for wid := range tr.clients {
-		found := false
-		for _, w := range clientList {
-			if w == wid {
-				found = true
-				break
-			}
+		if !contains(clientList, wid) {
+			tr.unTrack(wid)
 		}
+	}
+}
 
-		if !found {
-			tr.unTrack(wid)
+func contains(windows []xproto.Window, w xproto.Window) bool {
+	for _, win := range windows {
+		if win == w {
+			return true
 		}
 	}
+	return false
 }
 
 func (tr *tracker) IsTracked(w xproto.Window) bool {
@@ -62,79 +79,304 @@ func (tr *tracker) trackWindow(w xproto.Window) {
 		return
 	}
 
+	r := matchRules(w)
+	if r.Ignore {
+		tr.ignored[w] = true
+		return
+	}
+
 	c := newClient(w)
-	if c.Desk > state.DeskCount {
+	if c.Desk >= state.DeskCount {
 		return
 	}
+	applyRule(&c, r)
 	tr.attachHandlers(&c)
 
-	tr.clients[c.window.Id] = c
+	tr.clients[c.window] = &c
 	ws := tr.workspaces[c.Desk]
 	ws.AddClient(c)
-
+	publishWindow("new", c)
 }
 
 func (tr *tracker) unTrack(w xproto.Window) {
 	c, ok := tr.clients[w]
 	if ok {
 		ws := tr.workspaces[c.Desk]
-		ws.RemoveClient(c)
-		xevent.Detach(state.X, w)
+		ws.RemoveClient(*c)
+		backend.Unwatch(w)
 		delete(tr.clients, w)
+		publishWindow("close", *c)
 	}
 }
 
-func (tr *tracker) handleClientUpdates(X *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
-	if aname, _ := xprop.AtomName(state.X, ev.Atom); aname != "_NET_CLIENT_LIST_STACKING" {
-		return
+// toggleFloat takes a client out of the tiling, restoring its geometry, or puts it back in.
+func (tr *tracker) toggleFloat(c *Client) {
+	ws := tr.workspaces[c.Desk]
+	c.floatedBySticky = false
+	if c.floating {
+		c.floating = false
+		if geom, err := backend.Geometry(c.window); err == nil {
+			c.Head = backend.HeadForRect(geom)
+		}
+		ws.AddClient(*c)
+		publishWindow("tile", *c)
+	} else {
+		ws.RemoveClient(*c)
+		c.floating = true
+		if ws.IsTiling {
+			c.Restore()
+		}
+		publishWindow("float", *c)
 	}
 
+	ws.Tile()
+}
+
+// shutdown restores the geometry and decorations of every tiled client.
+// The state is saved beforehand, so that the workspaces are tiled again on the next start.
+func (tr *tracker) shutdown() {
+	tr.saveState()
+	for _, ws := range tr.workspaces {
+		if ws.IsTiling {
+			ws.Untile()
+		}
+	}
+
+	backend.Sync()
+}
+
+// publishWindow notifies the IPC subscribers about a change to a tracked window.
+func publishWindow(change string, c Client) {
+	ipc.Publish(ipc.Event{
+		Type:      "window",
+		Change:    change,
+		Workspace: c.Desk,
+		Head:      c.Head,
+		Window:    uint32(c.window),
+	})
+}
+
+// publishWorkspace notifies the IPC subscribers that a workspace was created or removed.
+func publishWorkspace(change string, num uint) {
+	ipc.Publish(ipc.Event{
+		Type:      "workspace",
+		Change:    change,
+		Workspace: num,
+	})
+}
+
+// rootHandlers handle the changes to the properties of the root window.
+var rootHandlers = map[string]func(tr *tracker){
+	"_NET_CURRENT_DESKTOP":      (*tracker).handleCurrentDesktop,
+	"_NET_NUMBER_OF_DESKTOPS":   (*tracker).updateWorkspaces,
+	"_NET_CLIENT_LIST_STACKING": (*tracker).handleClientUpdates,
+}
+
+// clientHandlers handle the changes to the properties of a tracked client.
+var clientHandlers = map[string]func(tr *tracker, c *Client){
+	"_NET_WM_STATE":   (*tracker).handleMinimizedClient,
+	"_NET_WM_DESKTOP": (*tracker).handleDesktopChange,
+}
+
+func (tr *tracker) handleCurrentDesktop() {
+	tr.moveStickyClients()
+	if ws, ok := tr.workspaces[state.CurrentDesk]; ok {
+		ws.publish("workspace")
+	}
+}
+
+func (tr *tracker) handleClientUpdates() {
 	tr.populateClients()
-	tr.workspaces[state.CurrentDesk].Tile()
+	if ws, ok := tr.workspaces[state.CurrentDesk]; ok {
+		ws.Tile()
+	}
+}
+
+// handleGeometryChange resizes the monitors of every workspace after the monitor layout changed,
+// moves clients to the monitor they are now on and retiles.
+func (tr *tracker) handleGeometryChange() {
+	count := monitorCount()
+	for desk, ws := range tr.workspaces {
+		ws.setMonitorCount(desk, count)
+	}
+
+	for _, c := range tr.clients {
+		head := c.Head
+		if geom, err := backend.Geometry(c.window); err == nil {
+			head = backend.HeadForRect(geom)
+		}
+
+		if head >= count {
+			head = count - 1
+		}
+
+		if head != c.Head {
+			ws := tr.workspaces[c.Desk]
+			ws.RemoveClient(*c)
+			c.Head = head
+			ws.AddClient(*c)
+		}
+	}
+
+	for _, ws := range tr.workspaces {
+		ws.Tile()
+	}
 }
 
 func (tr *tracker) handleMinimizedClient(c *Client) {
-	states, _ := ewmh.WmStateGet(state.X, c.window.Id)
-	for _, state := range states {
+	for _, state := range backend.States(c.window) {
 		if state == "_NET_WM_STATE_HIDDEN" {
 			tr.workspaces[c.Desk].RemoveClient(*c)
-			tr.unTrack(c.window.Id)
+			tr.unTrack(c.window)
 			tr.workspaces[c.Desk].Tile()
 		}
 	}
 }
 
+// updateWorkspaces creates or removes workspaces to match the number of desktops.
+// Clients of removed desktops are moved to the last remaining one.
+func (tr *tracker) updateWorkspaces() {
+	count := state.DeskCount
+	if count == 0 {
+		return
+	}
+
+	for i := uint(0); i < count; i++ {
+		if _, ok := tr.workspaces[i]; !ok {
+			tr.workspaces[i] = newWorkspace(i)
+			publishWorkspace("new", i)
+		}
+	}
+
+	last := tr.workspaces[count-1]
+	for _, c := range tr.clients {
+		if c.Desk < count {
+			continue
+		}
+
+		tr.workspaces[c.Desk].RemoveClient(*c)
+		c.Desk = count - 1
+		last.AddClient(*c)
+
+		// Like after a desktop change, clients moved to an untiled workspace get their geometry back.
+		if !last.IsTiling && !c.floating {
+			c.Restore()
+		}
+	}
+
+	for desk := range tr.workspaces {
+		if desk >= count {
+			delete(tr.workspaces, desk)
+			publishWorkspace("removed", desk)
+		}
+	}
+
+	last.Tile()
+}
+
+// moveStickyClients moves the sticky clients to the current desktop and retiles.
+func (tr *tracker) moveStickyClients() {
+	ws, ok := tr.workspaces[state.CurrentDesk]
+	if !ok {
+		return
+	}
+
+	moved := false
+	for _, c := range tr.clients {
+		if !c.sticky || c.Desk == state.CurrentDesk {
+			continue
+		}
+
+		tr.workspaces[c.Desk].RemoveClient(*c)
+		c.Desk = state.CurrentDesk
+		ws.AddClient(*c)
+		moved = moved || !c.floating
+	}
+
+	if moved {
+		ws.Tile()
+	}
+}
+
 func (tr *tracker) handleDesktopChange(c *Client) {
-	newDesk, _ := ewmh.WmDesktopGet(state.X, c.window.Id)
+	newDesk, _ := backend.Desktop(c.window)
+	sticky := newDesk == stickyDesk
+	if sticky {
+		newDesk = state.CurrentDesk
+	}
+
 	oldDesk := c.Desk
+	if (newDesk == oldDesk && sticky == c.sticky) || newDesk >= state.DeskCount {
+		return
+	}
 
 	tr.workspaces[oldDesk].RemoveClient(*c)
-	tr.workspaces[newDesk].AddClient(*c)
 
+	// Sticky clients float if they aren't tiled. Unsticking only tiles the clients
+	// that were floated by it, so that windows floated otherwise keep floating.
+	if sticky && !c.sticky && Config.Sticky != "tile" && !c.floating {
+		c.floating = true
+		c.floatedBySticky = true
+	} else if !sticky && c.sticky && c.floatedBySticky {
+		c.floating = false
+		c.floatedBySticky = false
+	}
+	c.sticky = sticky
 	c.Desk = newDesk
+	tr.workspaces[newDesk].AddClient(*c)
+
 	if tr.workspaces[oldDesk].IsTiling {
 		tr.workspaces[oldDesk].Tile()
 	}
 
 	if tr.workspaces[newDesk].IsTiling {
 		tr.workspaces[newDesk].Tile()
-	} else {
+	} else if !c.floating {
 		c.Restore()
 	}
 }
 
 func (tr *tracker) attachHandlers(c *Client) {
-	c.window.Listen(xproto.EventMaskPropertyChange)
+	handlers := make(map[string]func(), len(clientHandlers))
+	for prop, handle := range clientHandlers {
+		handle := handle
+		handlers[prop] = func() { handle(tr, c) }
+	}
+	backend.Watch(c.window, handlers)
+	backend.WatchGeometry(c.window, func(x, y, width, height int) {
+		tr.handleMove(c, c.checkGeometry(x, y, width, height))
+	})
+}
 
-	xevent.PropertyNotifyFun(func(x *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
-		if aname, _ := xprop.AtomName(state.X, ev.Atom); aname == "_NET_WM_STATE" {
-			tr.handleMinimizedClient(c)
-		}
-	}).Connect(state.X, c.window.Id)
+// handleMove moves a tiled client to the monitor its window was moved to, for example by dragging it.
+// The head of floating clients is updated when they are tiled again.
+func (tr *tracker) handleMove(c *Client, frame xrect.Rect) {
+	ws := tr.workspaces[c.Desk]
+	if c.floating || !ws.IsTiling {
+		return
+	}
+
+	// Clients that are where they were tiled haven't left their monitor.
+	if c.applied.geom != nil {
+		return
+	}
 
-	xevent.PropertyNotifyFun(func(x *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
-		if aname, _ := xprop.AtomName(state.X, ev.Atom); aname == "_NET_WM_DESKTOP" {
-			tr.handleDesktopChange(c)
+	// Only the moves reported by the window manager tell where the frame is.
+	if frame == nil {
+		geom, err := backend.Geometry(c.window)
+		if err != nil {
+			return
 		}
-	}).Connect(state.X, c.window.Id)
+		frame = geom
+	}
+
+	head := backend.HeadForRect(frame)
+	if head == c.Head || head >= monitorCount() {
+		return
+	}
+
+	ws.RemoveClient(*c)
+	c.Head = head
+	ws.AddClient(*c)
+	ws.Tile()
 }
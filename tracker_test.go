@@ -0,0 +1,358 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil/xrect"
+	"github.com/blrsn/zentile/state"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	// The tracker reads and writes its state, which must not be the one of the user.
+	dir, err := ioutil.TempDir("", "zentile-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("XDG_STATE_HOME", dir)
+	os.Unsetenv(restartEnv)
+	log.SetOutput(ioutil.Discard)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestTracker tracks the windows of a fake backend with two desktops and a head for each work area.
+func newTestTracker(areas ...xrect.Rect) (*fakeBackend, *tracker) {
+	fb := newFakeBackend(areas...)
+	backend = fb
+	Config = cfg{Proportion: 0.5}
+	state.DeskCount, state.CurrentDesk, state.ActiveWin = 2, 0, 0
+	return fb, initTracker(CreateWorkspaces())
+}
+
+// layoutClients returns the windows of the active layout of a monitor, masters first.
+func layoutClients(ws *Workspace, head uint) []xproto.Window {
+	var windows []xproto.Window
+	for _, c := range ws.monitor(head).ActiveLayout().sto().All() {
+		windows = append(windows, c.window)
+	}
+	return windows
+}
+
+func sameWindows(a, b []xproto.Window) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func frame(t *testing.T, fb *fakeBackend, w xproto.Window) xrect.Rect {
+	t.Helper()
+	geom, err := fb.Geometry(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return geom
+}
+
+func TestTrackWindows(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800))
+	Config.Rules = []Rule{{Class: "ignored", Ignore: true}}
+
+	a := fb.AddWindow(fakeWindow{width: 300, height: 200, decorated: true})
+	b := fb.AddWindow(fakeWindow{width: 300, height: 200, desk: 1, decorated: true})
+	ignored := fb.AddWindow(fakeWindow{class: "Ignored"})
+	dialog := fb.AddWindow(fakeWindow{types: []string{"_NET_WM_WINDOW_TYPE_DIALOG"}})
+
+	if len(tr.clients) != 3 {
+		t.Fatalf("%d clients are tracked, want 3", len(tr.clients))
+	}
+	if tr.IsTracked(ignored) || !tr.ignored[ignored] {
+		t.Error("window ignored by a rule is tracked")
+	}
+	if !tr.clients[dialog].floating {
+		t.Error("dialog isn't floating")
+	}
+	if got := layoutClients(tr.workspaces[0], 0); !sameWindows(got, []xproto.Window{a}) {
+		t.Errorf("workspace 0 has %v, want %v", got, []xproto.Window{a})
+	}
+	if got := layoutClients(tr.workspaces[1], 0); !sameWindows(got, []xproto.Window{b}) {
+		t.Errorf("workspace 1 has %v, want %v", got, []xproto.Window{b})
+	}
+	if tr.clients[a].Desk != 0 || tr.clients[b].Desk != 1 {
+		t.Error("clients are on the wrong desktop")
+	}
+
+	fb.RemoveWindow(ignored)
+	if len(tr.ignored) != 0 {
+		t.Error("closed window is still ignored")
+	}
+}
+
+func TestTileAndUntile(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800))
+	a := fb.AddWindow(fakeWindow{x: 10, y: 20, width: 300, height: 200, decorated: true})
+	b := fb.AddWindow(fakeWindow{x: 30, y: 40, width: 300, height: 200, decorated: true})
+
+	ws := tr.workspaces[0]
+	ws.IsTiling = true
+	ws.Tile()
+
+	if got, want := frame(t, fb, a), xrect.New(0, 0, 500, 800); !sameRect(got, want) {
+		t.Errorf("master is at %v, want %v", got, want)
+	}
+	if got, want := frame(t, fb, b), xrect.New(500, 0, 500, 800); !sameRect(got, want) {
+		t.Errorf("slave is at %v, want %v", got, want)
+	}
+
+	ws.Untile()
+	if got, want := frame(t, fb, a), xrect.New(10, 20, 302, 222); !sameRect(got, want) {
+		t.Errorf("untiled client is at %v, want %v", got, want)
+	}
+}
+
+func TestRemoveWindow(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800))
+	a := fb.AddWindow(fakeWindow{decorated: true})
+	b := fb.AddWindow(fakeWindow{decorated: true})
+	tr.workspaces[0].IsTiling = true
+
+	fb.RemoveWindow(b)
+	if tr.IsTracked(b) {
+		t.Error("removed window is still tracked")
+	}
+	if got := layoutClients(tr.workspaces[0], 0); !sameWindows(got, []xproto.Window{a}) {
+		t.Errorf("workspace has %v, want %v", got, []xproto.Window{a})
+	}
+	if got, want := frame(t, fb, a), xrect.New(0, 0, 1000, 800); !sameRect(got, want) {
+		t.Errorf("remaining client is at %v, want %v", got, want)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800))
+	a := fb.AddWindow(fakeWindow{decorated: true})
+
+	fb.SetStates(a, "_NET_WM_STATE_HIDDEN")
+	if tr.IsTracked(a) {
+		t.Error("minimized window is still tracked")
+	}
+	if got := layoutClients(tr.workspaces[0], 0); len(got) != 0 {
+		t.Errorf("workspace has %v, want none", got)
+	}
+}
+
+func TestDesktopChange(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800))
+	a := fb.AddWindow(fakeWindow{decorated: true})
+	tr.workspaces[1].IsTiling = true
+
+	fb.SetDesktop(a, 1)
+	if tr.clients[a].Desk != 1 {
+		t.Errorf("client is on desktop %d, want 1", tr.clients[a].Desk)
+	}
+	if got := layoutClients(tr.workspaces[0], 0); len(got) != 0 {
+		t.Errorf("workspace 0 has %v, want none", got)
+	}
+	if got := layoutClients(tr.workspaces[1], 0); !sameWindows(got, []xproto.Window{a}) {
+		t.Errorf("workspace 1 has %v, want %v", got, []xproto.Window{a})
+	}
+	if got, want := frame(t, fb, a), xrect.New(0, 0, 1000, 800); !sameRect(got, want) {
+		t.Errorf("client is at %v, want %v", got, want)
+	}
+}
+
+func TestToggleFloat(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800))
+	a := fb.AddWindow(fakeWindow{x: 10, y: 20, width: 300, height: 200, decorated: true})
+	b := fb.AddWindow(fakeWindow{decorated: true})
+	ws := tr.workspaces[0]
+	ws.IsTiling = true
+	ws.Tile()
+
+	tr.toggleFloat(tr.clients[a])
+	if got := layoutClients(ws, 0); !sameWindows(got, []xproto.Window{b}) {
+		t.Errorf("workspace has %v, want %v", got, []xproto.Window{b})
+	}
+	if got, want := frame(t, fb, a), xrect.New(10, 20, 302, 222); !sameRect(got, want) {
+		t.Errorf("floating client is at %v, want %v", got, want)
+	}
+
+	tr.toggleFloat(tr.clients[a])
+	if got := layoutClients(ws, 0); len(got) != 2 {
+		t.Errorf("workspace has %v, want both clients", got)
+	}
+}
+
+func TestSticky(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800))
+	a := fb.AddWindow(fakeWindow{decorated: true})
+	b := fb.AddWindow(fakeWindow{decorated: true})
+
+	// Sticky windows float, and are tiled again when unstuck.
+	fb.SetDesktop(a, stickyDesk)
+	if c := tr.clients[a]; !c.sticky || !c.floating {
+		t.Error("sticky client isn't floating")
+	}
+	fb.SetDesktop(a, 0)
+	if c := tr.clients[a]; c.sticky || c.floating {
+		t.Error("unstuck client is still floating")
+	}
+
+	// Windows floated by hand keep floating.
+	tr.toggleFloat(tr.clients[b])
+	fb.SetDesktop(b, stickyDesk)
+	fb.SetDesktop(b, 0)
+	if !tr.clients[b].floating {
+		t.Error("client floated by hand was tiled when unstuck")
+	}
+}
+
+func TestHeads(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800), xrect.New(1000, 0, 1000, 800))
+	a := fb.AddWindow(fakeWindow{x: 100, width: 300, height: 200, decorated: true})
+	b := fb.AddWindow(fakeWindow{x: 1100, width: 300, height: 200, decorated: true})
+	ws := tr.workspaces[0]
+	ws.IsTiling = true
+	ws.Tile()
+
+	if tr.clients[a].Head != 0 || tr.clients[b].Head != 1 {
+		t.Fatal("clients are on the wrong head")
+	}
+	if got, want := frame(t, fb, b), xrect.New(1000, 0, 1000, 800); !sameRect(got, want) {
+		t.Errorf("client of the second head is at %v, want %v", got, want)
+	}
+
+	fb.SetAreas(xrect.New(0, 0, 1000, 800))
+	if len(ws.monitors) != 1 {
+		t.Errorf("workspace has %d monitors, want 1", len(ws.monitors))
+	}
+	if tr.clients[b].Head != 0 {
+		t.Error("client of the removed head wasn't moved")
+	}
+	if got := layoutClients(ws, 0); len(got) != 2 {
+		t.Errorf("remaining monitor has %v, want both clients", got)
+	}
+}
+
+func TestNoHeads(t *testing.T) {
+	fb, tr := newTestTracker()
+	a := fb.AddWindow(fakeWindow{decorated: true})
+	ws := tr.workspaces[0]
+	ws.IsTiling = true
+	ws.Tile()
+	ws.SwitchLayout()
+
+	if got := layoutClients(ws, 0); !sameWindows(got, []xproto.Window{a}) {
+		t.Errorf("workspace has %v, want %v", got, []xproto.Window{a})
+	}
+
+	ws.monitors = nil
+	if ws.ActiveMonitor() == nil {
+		t.Error("workspace without monitors has no active monitor")
+	}
+}
+
+func TestRetileAfterMove(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800))
+	a := fb.AddWindow(fakeWindow{decorated: true})
+	b := fb.AddWindow(fakeWindow{decorated: true})
+	ws := tr.workspaces[0]
+	ws.IsTiling = true
+	ws.Tile()
+
+	if tr.clients[a].applied.geom == nil || tr.clients[b].applied.geom == nil {
+		t.Fatal("geometry of the tiled clients isn't cached")
+	}
+
+	// The events of the moved window tell that it is out of place.
+	fb.Move(a, 100, 100)
+	if tr.clients[a].applied.geom != nil {
+		t.Error("geometry of the moved client is still cached")
+	}
+	if tr.clients[b].applied.geom == nil {
+		t.Error("geometry of the client in place was forgotten")
+	}
+
+	ws.Tile()
+	if got, want := frame(t, fb, a), xrect.New(0, 0, 500, 800); !sameRect(got, want) {
+		t.Errorf("moved client is at %v, want %v", got, want)
+	}
+}
+
+func TestMoveToOtherHead(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800), xrect.New(1000, 0, 1000, 800))
+	a := fb.AddWindow(fakeWindow{x: 100, width: 300, height: 200, decorated: true})
+	b := fb.AddWindow(fakeWindow{x: 200, width: 300, height: 200, decorated: true})
+	ws := tr.workspaces[0]
+	ws.IsTiling = true
+	ws.Tile()
+
+	fb.Move(b, 1200, 100)
+	if tr.clients[b].Head != 1 {
+		t.Fatalf("dragged client is on head %d, want 1", tr.clients[b].Head)
+	}
+	if got := layoutClients(ws, 0); !sameWindows(got, []xproto.Window{a}) {
+		t.Errorf("first head has %v, want %v", got, []xproto.Window{a})
+	}
+	if got, want := frame(t, fb, b), xrect.New(1000, 0, 1000, 800); !sameRect(got, want) {
+		t.Errorf("dragged client is at %v, want %v", got, want)
+	}
+	if got, want := frame(t, fb, a), xrect.New(0, 0, 1000, 800); !sameRect(got, want) {
+		t.Errorf("remaining client is at %v, want %v", got, want)
+	}
+}
+
+func TestRemoveDesktop(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800))
+	Config.HideDecor = true
+	a := fb.AddWindow(fakeWindow{x: 10, y: 20, width: 300, height: 200, desk: 1, decorated: true})
+	tr.workspaces[1].IsTiling = true
+	tr.workspaces[1].Tile()
+
+	state.DeskCount = 1
+	fb.notify(fakeRoot, "_NET_NUMBER_OF_DESKTOPS")
+
+	if len(tr.workspaces) != 1 || tr.clients[a].Desk != 0 {
+		t.Fatal("client wasn't moved to the remaining workspace")
+	}
+	if got, want := frame(t, fb, a), xrect.New(10, 20, 302, 222); !sameRect(got, want) {
+		t.Errorf("client moved to the untiled workspace is at %v, want %v", got, want)
+	}
+	if !fb.windows[a].decorated {
+		t.Error("client moved to the untiled workspace has no decorations")
+	}
+}
+
+func TestMoveFloatingClient(t *testing.T) {
+	fb, tr := newTestTracker(xrect.New(0, 0, 1000, 800), xrect.New(1000, 0, 1000, 800))
+	a := fb.AddWindow(fakeWindow{x: 100, width: 300, height: 200, decorated: true})
+	ws := tr.workspaces[0]
+	ws.IsTiling = true
+	ws.Tile()
+	tr.toggleFloat(tr.clients[a])
+
+	// Floating clients are left alone until they are tiled again.
+	fb.Move(a, 1200, 100)
+	if tr.clients[a].Head != 0 {
+		t.Error("head of the floating client was updated on move")
+	}
+
+	tr.toggleFloat(tr.clients[a])
+	if tr.clients[a].Head != 1 {
+		t.Errorf("client tiled again is on head %d, want 1", tr.clients[a].Head)
+	}
+	if got := layoutClients(ws, 1); !sameWindows(got, []xproto.Window{a}) {
+		t.Errorf("second head has %v, want %v", got, []xproto.Window{a})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/blrsn/zentile/state"
+)
+
+// treeWorkspace is the structured view of a workspace returned by the get_tree query.
+type treeWorkspace struct {
+	Num      uint          `json:"num"`
+	IsTiling bool          `json:"is_tiling"`
+	Current  bool          `json:"current"`
+	Monitors []treeMonitor `json:"monitors"`
+}
+
+type treeMonitor struct {
+	Head           uint         `json:"head"`
+	Layout         string       `json:"layout"`
+	Proportion     float64      `json:"proportion,omitempty"`
+	AllowedMasters int          `json:"allowed_masters"`
+	Masters        []treeClient `json:"masters"`
+	Slaves         []treeClient `json:"slaves"`
+}
+
+type treeClient struct {
+	Id       uint32 `json:"id"`
+	Class    string `json:"class"`
+	Instance string `json:"instance"`
+	Title    string `json:"title"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+// tree returns the workspaces, ordered by number, along with the layouts and clients of each monitor.
+func (t *tracker) tree() []treeWorkspace {
+	workspaces := make([]treeWorkspace, 0, len(t.workspaces))
+	for num, ws := range t.workspaces {
+		workspaces = append(workspaces, ws.tree(num))
+	}
+
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Num < workspaces[j].Num })
+	return workspaces
+}
+
+func (ws *Workspace) tree(num uint) treeWorkspace {
+	tw := treeWorkspace{
+		Num:      num,
+		IsTiling: ws.IsTiling,
+		Current:  num == state.CurrentDesk,
+		Monitors: make([]treeMonitor, 0, len(ws.monitors)),
+	}
+
+	for h, m := range ws.monitors {
+		l := m.ActiveLayout()
+		st := l.sto()
+		tm := treeMonitor{
+			Head:           uint(h),
+			Layout:         l.Name(),
+			AllowedMasters: st.allowedMasters,
+			Masters:        treeClients(st.masters),
+			Slaves:         treeClients(st.slaves),
+		}
+
+		if p, ok := l.(interface{ proportion() float64 }); ok {
+			tm.Proportion = p.proportion()
+		}
+
+		tw.Monitors = append(tw.Monitors, tm)
+	}
+
+	return tw
+}
+
+func treeClients(clients []Client) []treeClient {
+	tcs := make([]treeClient, 0, len(clients))
+	for _, c := range clients {
+		tc := treeClient{
+			Id:    uint32(c.window),
+			Title: c.name(),
+		}
+
+		tc.Class, tc.Instance, _ = backend.Class(c.window)
+
+		if geom, err := backend.Geometry(c.window); err == nil {
+			tc.X, tc.Y, tc.Width, tc.Height = geom.Pieces()
+		}
+
+		tcs = append(tcs, tc)
+	}
+
+	return tcs
+}
+
+// previewMonitor is where a layout would place the windows of a monitor, returned by the preview_layout query.
+type previewMonitor struct {
+	Head    uint            `json:"head"`
+	Layout  string          `json:"layout"`
+	Windows []previewWindow `json:"windows"`
+}
+
+type previewWindow struct {
+	Id     uint32 `json:"id"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// preview computes the geometry of the named layout on every monitor, without moving any window.
+// The windows are in the order of the layout, masters first.
+func (ws *Workspace) preview(name string) ([]previewMonitor, error) {
+	previews := make([]previewMonitor, 0, len(ws.monitors))
+	for h, m := range ws.monitors {
+		l := m.layout(name)
+		if l == nil {
+			return nil, fmt.Errorf("unknown layout %q", name)
+		}
+
+		st := l.sto()
+		pm := previewMonitor{Head: uint(h), Layout: name}
+		rects := l.geometry(arrange(st, ws.num, uint(h)))
+		for i, c := range st.All() {
+			x, y, width, height := rects[i].Pieces()
+			pm.Windows = append(pm.Windows, previewWindow{uint32(c.window), x, y, width, height})
+		}
+		previews = append(previews, pm)
+	}
+
+	return previews, nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/BurntSushi/xgbutil"
+	"github.com/BurntSushi/xgbutil/keybind"
+)
+
+// MAX_GAP is the largest gap between windows that is accepted.
+const MAX_GAP = 200
+
+// configProblem is an issue found in the config file.
+// Zentile refuses to use a config with fatal problems.
+type configProblem struct {
+	line  int // Zero if the line is unknown.
+	msg   string
+	fatal bool
+}
+
+func (p configProblem) String() string {
+	if p.line > 0 {
+		return fmt.Sprintf("%s:%d: %s", configFilePath(), p.line, p.msg)
+	}
+	return fmt.Sprintf("%s: %s", configFilePath(), p.msg)
+}
+
+// readConfig decodes and validates the config file.
+// Key sequences are only checked for valid keys if X is not nil.
+func readConfig(X *xgbutil.XUtil) (cfg, []configProblem) {
+	var c cfg
+	src, err := ioutil.ReadFile(configFilePath())
+	if err != nil {
+		return c, []configProblem{{msg: err.Error(), fatal: true}}
+	}
+
+	md, err := toml.Decode(string(src), &c)
+	if err != nil {
+		return c, []configProblem{{msg: err.Error(), fatal: true}}
+	}
+
+	v := validator{src: src, md: md}
+	if err := c.compileRules(); err != nil {
+		v.fatal(0, err.Error())
+	}
+
+	v.checkUndecoded()
+	v.checkValues(c)
+	v.checkKeybindings(c, X)
+	return c, v.problems
+}
+
+type validator struct {
+	src      []byte
+	md       toml.MetaData
+	problems []configProblem
+}
+
+func (v *validator) fatal(line int, format string, args ...interface{}) {
+	v.problems = append(v.problems, configProblem{line, fmt.Sprintf(format, args...), true})
+}
+
+func (v *validator) warn(line int, format string, args ...interface{}) {
+	v.problems = append(v.problems, configProblem{line, fmt.Sprintf(format, args...), false})
+}
+
+// checkUndecoded reports keys that don't correspond to any setting, usually typos.
+func (v *validator) checkUndecoded() {
+	for _, key := range v.md.Undecoded() {
+		v.warn(v.keyLine(key...), "unknown key '%s'", key)
+	}
+}
+
+func (v *validator) checkValues(c cfg) {
+	if v.md.IsDefined("proportion") && (c.Proportion <= 0 || c.Proportion >= MASTER_MAX_PROPORTION-MASTER_MIN_PROPORTION) {
+		v.fatal(v.keyLine("proportion"), "proportion must be between 0 and %.1f, got %v",
+			MASTER_MAX_PROPORTION-MASTER_MIN_PROPORTION, c.Proportion)
+	}
+
+	if c.Gap < 0 || c.Gap > MAX_GAP {
+		v.fatal(v.keyLine("gap"), "gap must be between 0 and %d, got %d", MAX_GAP, c.Gap)
+	}
+
+	if c.Sticky != "" && c.Sticky != "float" && c.Sticky != "tile" {
+		v.fatal(v.keyLine("sticky"), "sticky must be \"float\" or \"tile\", got %q", c.Sticky)
+	}
+
+	for i, r := range c.Rules {
+		if r.Width < 0 || r.Height < 0 {
+			v.fatal(0, "rule %d: width and height can't be negative", i+1)
+		}
+	}
+}
+
+// checkKeybindings reports unknown actions, invalid key sequences and keys bound to several actions.
+func (v *validator) checkKeybindings(c cfg, X *xgbutil.XUtil) {
+	names := make([]string, 0, len(c.Keybindings))
+	for name := range c.Keybindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bound := make(map[string]string)
+	for _, name := range names {
+		seq := c.Keybindings[name]
+		line := v.keyLine("keybindings", name)
+		if _, ok := actions[name]; !ok {
+			v.warn(line, "unknown action '%s'", name)
+		}
+
+		if err := checkKeySequence(X, seq); err != nil {
+			v.fatal(line, "invalid key sequence %q for %s: %v", seq, name, err)
+			continue
+		}
+
+		norm := normalizeKeySequence(seq)
+		if other, ok := bound[norm]; ok {
+			v.fatal(line, "%q is bound to both %s and %s", seq, other, name)
+		}
+		bound[norm] = name
+	}
+}
+
+// checkKeySequence checks the modifiers of a key sequence, and the key if X is not nil.
+func checkKeySequence(X *xgbutil.XUtil, seq string) error {
+	parts := strings.Split(seq, "-")
+	for _, mod := range parts[:len(parts)-1] {
+		if !isModifier(mod) {
+			return fmt.Errorf("unknown modifier '%s'", mod)
+		}
+	}
+
+	if key := parts[len(parts)-1]; key == "" || isModifier(key) {
+		return errors.New("missing key")
+	}
+
+	if X != nil {
+		if _, _, err := keybind.ParseString(X, seq); err != nil {
+			return fmt.Errorf("unknown key '%s'", parts[len(parts)-1])
+		}
+	}
+	return nil
+}
+
+func isModifier(s string) bool {
+	s = strings.ToLower(s)
+	for _, mod := range keybind.NiceModifiers {
+		if mod != "" && s == mod {
+			return true
+		}
+	}
+	return s == "any"
+}
+
+// normalizeKeySequence returns the key sequence with sorted, lower case modifiers.
+func normalizeKeySequence(seq string) string {
+	parts := strings.Split(strings.ToLower(seq), "-")
+	mods := parts[:len(parts)-1]
+	sort.Strings(mods)
+	return strings.Join(append(mods, parts[len(parts)-1]), "-")
+}
+
+// keyLine returns the line of the config file where the key is set, or zero if it can't be found.
+// Only plain keys and [table] or [[array]] headers are recognized.
+func (v *validator) keyLine(key ...string) int {
+	table := strings.Join(key[:len(key)-1], ".")
+	name := key[len(key)-1]
+
+	current := ""
+	for i, line := range strings.Split(string(v.src), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			current = strings.Trim(line, "[] ")
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq < 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if current == table && strings.Trim(strings.TrimSpace(line[:eq]), `"'`) == name {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// checkConfigCommand runs the check-config subcommand and returns its exit status.
+func checkConfigCommand() int {
+	X, err := xgbutil.NewConn()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Can't connect to X, keys in key sequences are not checked:", err)
+		X = nil
+	} else {
+		keybind.Initialize(X)
+	}
+
+	_, problems := readConfig(X)
+	status := 0
+	for _, p := range problems {
+		fmt.Println(p)
+		if p.fatal {
+			status = 1
+		}
+	}
+
+	if len(problems) == 0 {
+		fmt.Println(configFilePath() + ": ok")
+	}
+	return status
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"github.com/blrsn/zentile/state"
+	"github.com/BurntSushi/xgbutil/xrect"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -9,92 +9,72 @@ type VerticalLayout struct {
 	*VertHorz
 }
 
+func (l *VerticalLayout) Name() string {
+	return "vertical"
+}
+
 func (l *VerticalLayout) Do() {
 	log.Info("Switching to Vertical Layout")
-	wx, wy, ww, wh := state.WorkAreaDimensions(l.WorkspaceNum)
-	msize := len(l.masters)
-	ssize := len(l.slaves)
+	prepareTiling(l.All())
+	applyLayout(l, l.WorkspaceNum, l.HeadNum)
+}
+
+func (l *VerticalLayout) geometry(a arrangement) []xrect.Rect {
+	wx, wy, ww, wh := a.area.Pieces()
+	msize := len(a.masters)
+	ssize := len(a.slaves)
 
 	mx := wx
 	mw := int(float64(ww) * l.Proportion)
 	sx := mx + mw
 	sw := ww - mw
-	gap := Config.Gap
-
-	if msize > 0 {
-		mh := (wh - (msize+1)*gap) / msize
-		if ssize == 0 {
-			mw = ww
-		}
-
-		for i, c := range l.masters {
-			if Config.HideDecor {
-				c.UnDecorate()
-			}
-			c.MoveResize(mx+gap, gap+wy+i*(mh+gap), mw-2*gap, mh)
-		}
+	gap := a.gap
+
+	if ssize == 0 {
+		mw = ww
 	}
 
-	if ssize > 0 {
-		sh := (wh - (ssize+1)*gap) / ssize
-		if msize == 0 {
-			sx, sw = wx, ww
-		}
-
-		for i, c := range l.slaves {
-			if Config.HideDecor {
-				c.UnDecorate()
-			}
-			c.MoveResize(sx, gap+wy+i*(sh+gap), sw-gap, sh)
-		}
+	if msize == 0 {
+		sx, sw = wx, ww
 	}
 
-	state.X.Conn().Sync()
+	rects := column(a.masters, mx+gap, wy+gap, mw-2*gap, wh-2*gap, gap)
+	return append(rects, column(a.slaves, sx, wy+gap, sw-gap, wh-2*gap, gap)...)
 }
 
 type HorizontalLayout struct {
 	*VertHorz
 }
 
+func (l *HorizontalLayout) Name() string {
+	return "horizontal"
+}
+
 func (l *HorizontalLayout) Do() {
 	log.Info("Switching to Horizontal Layout")
-	wx, wy, ww, wh := state.WorkAreaDimensions(l.WorkspaceNum)
-	msize := len(l.masters)
-	ssize := len(l.slaves)
+	prepareTiling(l.All())
+	applyLayout(l, l.WorkspaceNum, l.HeadNum)
+}
+
+func (l *HorizontalLayout) geometry(a arrangement) []xrect.Rect {
+	wx, wy, ww, wh := a.area.Pieces()
+	msize := len(a.masters)
+	ssize := len(a.slaves)
 
 	my := wy
 	mh := int(float64(wh) * l.Proportion)
 	sy := my + mh
 	sh := wh - mh
-	gap := Config.Gap
-
-	if msize > 0 {
-		mw := (ww - (msize+1)*gap) / msize
-		if ssize == 0 {
-			mh = wh
-		}
-
-		for i, c := range l.masters {
-			if Config.HideDecor {
-				c.UnDecorate()
-			}
-			c.MoveResize(gap+wx+i*(mw+gap), my+gap, mw, mh-2*gap)
-		}
+	gap := a.gap
+
+	if ssize == 0 {
+		mh = wh
 	}
 
-	if ssize > 0 {
-		sw := (ww - (ssize+1)*gap) / ssize
-		if msize == 0 {
-			sy, sh = wy, wh
-		}
-
-		for i, c := range l.slaves {
-			if Config.HideDecor {
-				c.UnDecorate()
-			}
-			c.MoveResize(gap+wx+i*(sw+gap), sy, sw, sh-gap)
-		}
+	if msize == 0 {
+		sy, sh = wy, wh
 	}
 
-	state.X.Conn().Sync()
+	rects := row(a.masters, wx+gap, my+gap, ww-2*gap, mh-2*gap, gap)
+	return append(rects, row(a.slaves, wx+gap, sy, ww-2*gap, sh-gap, gap)...)
 }
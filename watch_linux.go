@@ -0,0 +1,69 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// watchConfig returns a channel that receives a value whenever the config file is written.
+// The folder is watched rather than the file, since editors often replace the file when saving.
+func watchConfig() <-chan struct{} {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		log.Warn("Error watching config: ", err)
+		return nil
+	}
+
+	mask := uint32(syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO)
+	if _, err := syscall.InotifyAddWatch(fd, configFolderPath(), mask); err != nil {
+		log.Warn("Error watching config: ", err)
+		syscall.Close(fd)
+		return nil
+	}
+
+	changes := make(chan struct{}, 1)
+	go readInotify(fd, filepath.Base(configFilePath()), changes)
+	return changes
+}
+
+func readInotify(fd int, name string, changes chan struct{}) {
+	defer syscall.Close(fd)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := syscall.Read(fd, buf)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			log.Warn("Error watching config: ", err)
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(ev.Len)]
+			offset += syscall.SizeofInotifyEvent + int(ev.Len)
+
+			if cString(nameBytes) == name {
+				notify(changes)
+			}
+		}
+	}
+}
+
+// cString returns the string in a NUL padded byte slice.
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+// watchConfig is only supported on linux, the config can still be reloaded with the reload_config action.
+func watchConfig() <-chan struct{} {
+	return nil
+}
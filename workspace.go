@@ -3,11 +3,18 @@ package main
 import (
 	"fmt"
 
+	"github.com/blrsn/zentile/ipc"
 	"github.com/blrsn/zentile/state"
 )
 
 type Workspace struct {
-	IsTiling        bool
+	IsTiling bool
+	num      uint
+	monitors []*Monitor
+}
+
+// Monitor holds the layouts of a single head in a workspace.
+type Monitor struct {
 	activeLayoutNum uint
 	layouts         []Layout
 }
@@ -15,84 +22,203 @@ type Workspace struct {
 func CreateWorkspaces() map[uint]*Workspace {
 	workspaces := make(map[uint]*Workspace)
 	for i := uint(0); i < state.DeskCount; i++ {
-		ws := Workspace{
-			IsTiling: false,
-			layouts:  createLayouts(i),
-		}
-
-		workspaces[i] = &ws
+		workspaces[i] = newWorkspace(i)
 	}
 
 	return workspaces
 }
 
-func createLayouts(workspaceNum uint) []Layout {
+func newWorkspace(num uint) *Workspace {
+	return &Workspace{
+		IsTiling: false,
+		num:      num,
+		monitors: createMonitors(num),
+	}
+}
+
+func createMonitors(workspaceNum uint) []*Monitor {
+	monitors := make([]*Monitor, monitorCount())
+	for h := range monitors {
+		monitors[h] = &Monitor{layouts: createLayouts(workspaceNum, uint(h))}
+	}
+
+	return monitors
+}
+
+func createLayouts(workspaceNum, headNum uint) []Layout {
 	return []Layout{
 		&VerticalLayout{&VertHorz{
 			Store:        buildStore(),
 			Proportion:   0.5,
 			WorkspaceNum: workspaceNum,
+			HeadNum:      headNum,
 		}},
 		&HorizontalLayout{&VertHorz{
 			Store:        buildStore(),
 			Proportion:   0.5,
 			WorkspaceNum: workspaceNum,
+			HeadNum:      headNum,
+		}},
+		&CenterLayout{&VertHorz{
+			Store:        buildStore(),
+			Proportion:   0.5,
+			WorkspaceNum: workspaceNum,
+			HeadNum:      headNum,
 		}},
+		&SpiralLayout{&VertHorz{
+			Store:        buildStore(),
+			Proportion:   0.5,
+			WorkspaceNum: workspaceNum,
+			HeadNum:      headNum,
+		}},
+		&GridLayout{
+			Store:        buildStore(),
+			WorkspaceNum: workspaceNum,
+			HeadNum:      headNum,
+		},
 		&FullScreen{
 			Store:        buildStore(),
 			WorkspaceNum: workspaceNum,
+			HeadNum:      headNum,
 		},
 	}
 }
 
+// monitorCount returns the number of monitors of a workspace, one for each head.
+// There is at least one, so that clients always have layouts, even if no head is known.
+func monitorCount() uint {
+	if n := backend.HeadCount(); n > 0 {
+		return n
+	}
+	return 1
+}
+
+// setMonitorCount adds or removes monitors to match the number of heads.
+// Clients of removed monitors have to be added to the remaining ones by the caller.
+func (ws *Workspace) setMonitorCount(workspaceNum, count uint) {
+	if count < uint(len(ws.monitors)) {
+		ws.monitors = ws.monitors[:count]
+	}
+
+	for h := uint(len(ws.monitors)); h < count; h++ {
+		ws.monitors = append(ws.monitors, &Monitor{layouts: createLayouts(workspaceNum, h)})
+	}
+}
+
+func (m *Monitor) ActiveLayout() Layout {
+	return m.layouts[m.activeLayoutNum]
+}
+
+// layout returns the layout with the given name, or nil if there is none.
+func (m *Monitor) layout(name string) Layout {
+	for _, l := range m.layouts {
+		if l.Name() == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// monitor returns the monitor for a head, falling back to the last one for unknown heads.
+func (ws *Workspace) monitor(head uint) *Monitor {
+	if len(ws.monitors) == 0 {
+		ws.setMonitorCount(ws.num, 1)
+	}
+
+	if head >= uint(len(ws.monitors)) {
+		head = uint(len(ws.monitors)) - 1
+	}
+	return ws.monitors[head]
+}
+
+// ActiveMonitor returns the monitor that has the active window, or the pointer.
+func (ws *Workspace) ActiveMonitor() *Monitor {
+	return ws.monitor(backend.ActiveHead())
+}
+
+// ActiveLayout returns the active layout of the active monitor.
 func (ws *Workspace) ActiveLayout() Layout {
-	return ws.layouts[ws.activeLayoutNum]
+	return ws.ActiveMonitor().ActiveLayout()
 }
 
-// Cycle through the available layouts
+// Cycle through the available layouts of the active monitor
 func (ws *Workspace) SwitchLayout() {
-	ws.activeLayoutNum = (ws.activeLayoutNum + 1) % uint(len(ws.layouts))
-	ws.ActiveLayout().Do()
+	m := ws.ActiveMonitor()
+	m.activeLayoutNum = (m.activeLayoutNum + 1) % uint(len(m.layouts))
+	m.ActiveLayout().Do()
+	ws.publish("layout")
 }
 
-// Adds client to all the layouts in a workspace
+// Adds client to all the layouts of its monitor, unless it is floating
 func (ws *Workspace) AddClient(c Client) {
-	for _, l := range ws.layouts {
+	if c.floating {
+		return
+	}
+
+	for _, l := range ws.monitor(c.Head).layouts {
 		l.Add(c)
 	}
 }
 
 // Removes client from all the layouts in a workspace
 func (ws *Workspace) RemoveClient(c Client) {
-	for _, l := range ws.layouts {
-		l.Remove(c)
+	for _, m := range ws.monitors {
+		for _, l := range m.layouts {
+			l.Remove(c)
+		}
 	}
 }
 
-// Tiles the active layout in a workspace
+// Tiles the active layout of every monitor in a workspace
 func (ws *Workspace) Tile() {
 	if ws.IsTiling {
-		ws.ActiveLayout().Do()
+		for _, m := range ws.monitors {
+			m.ActiveLayout().Do()
+		}
 	}
 }
 
-// Untiles the active layout in a workspace.
+// Untiles the active layout of every monitor in a workspace.
 func (ws *Workspace) Untile() {
 	ws.IsTiling = false
-	ws.ActiveLayout().Undo()
+	for _, m := range ws.monitors {
+		m.ActiveLayout().Undo()
+	}
+	ws.publish("untile")
 }
 
-func (ws *Workspace) printStore() {
-	l := ws.ActiveLayout()
-	st := l.sto()
-	fmt.Println("Number of masters is ", len(st.masters))
-	fmt.Println("Number of slaves is", len(st.slaves))
-
-	for i, c := range st.masters {
-		fmt.Println("master ", " ", i, " - ", c.name())
+// publish notifies the IPC subscribers about a change in the workspace.
+func (ws *Workspace) publish(eventType string) {
+	// Finding the active head takes requests to the X server.
+	if !ipc.HasSubscribers() {
+		return
 	}
 
-	for i, c := range st.slaves {
-		fmt.Println("slave ", " ", i, " - ", c.name())
+	head := backend.ActiveHead()
+	l := ws.monitor(head).ActiveLayout()
+	ipc.Publish(ipc.Event{
+		Type:      eventType,
+		Workspace: ws.num,
+		Head:      head,
+		IsTiling:  ws.IsTiling,
+		Layout:    l.Name(),
+		Masters:   len(l.sto().masters),
+	})
+}
+
+func (ws *Workspace) printStore() {
+	for h, m := range ws.monitors {
+		st := m.ActiveLayout().sto()
+		fmt.Println("Monitor ", h)
+		fmt.Println("Number of masters is ", len(st.masters))
+		fmt.Println("Number of slaves is", len(st.slaves))
+
+		for i, c := range st.masters {
+			fmt.Println("master ", " ", i, " - ", c.name())
+		}
+
+		for i, c := range st.slaves {
+			fmt.Println("slave ", " ", i, " - ", c.name())
+		}
 	}
 }
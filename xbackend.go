@@ -0,0 +1,228 @@
+package main
+
+import (
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil"
+	"github.com/BurntSushi/xgbutil/ewmh"
+	"github.com/BurntSushi/xgbutil/icccm"
+	"github.com/BurntSushi/xgbutil/motif"
+	"github.com/BurntSushi/xgbutil/xevent"
+	"github.com/BurntSushi/xgbutil/xprop"
+	"github.com/BurntSushi/xgbutil/xrect"
+	"github.com/BurntSushi/xgbutil/xwindow"
+	"github.com/blrsn/zentile/state"
+	log "github.com/sirupsen/logrus"
+)
+
+// xBackend is the Backend of an X server with an EWMH compliant window manager.
+type xBackend struct {
+	X      *xgbutil.XUtil
+	events map[xproto.Window]int // Event masks selected on the watched windows.
+}
+
+func newXBackend(X *xgbutil.XUtil) *xBackend {
+	return &xBackend{X: X, events: make(map[xproto.Window]int)}
+}
+
+func (b *xBackend) Geometry(w xproto.Window) (xrect.Rect, error) {
+	return xwindow.New(b.X, w).DecorGeometry()
+}
+
+func (b *xBackend) ClientGeometry(w xproto.Window) (xrect.Rect, error) {
+	return xwindow.RawGeometry(b.X, xproto.Drawable(w))
+}
+
+func (b *xBackend) Desktop(w xproto.Window) (uint, error) {
+	return ewmh.WmDesktopGet(b.X, w)
+}
+
+func (b *xBackend) Class(w xproto.Window) (string, string, error) {
+	c, err := icccm.WmClassGet(b.X, w)
+	if err != nil {
+		return "", "", err
+	}
+	return c.Class, c.Instance, nil
+}
+
+func (b *xBackend) Name(w xproto.Window) string {
+	name, _ := ewmh.WmNameGet(b.X, w)
+	if name == "" {
+		name, _ = icccm.WmNameGet(b.X, w)
+	}
+	return name
+}
+
+func (b *xBackend) Role(w xproto.Window) string {
+	role, _ := xprop.PropValStr(xprop.GetProperty(b.X, w, "WM_WINDOW_ROLE"))
+	return role
+}
+
+func (b *xBackend) Types(w xproto.Window) []string {
+	types, _ := ewmh.WmWindowTypeGet(b.X, w)
+	return types
+}
+
+func (b *xBackend) States(w xproto.Window) []string {
+	states, _ := ewmh.WmStateGet(b.X, w)
+	return states
+}
+
+func (b *xBackend) TransientFor(w xproto.Window) xproto.Window {
+	parent, err := icccm.WmTransientForGet(b.X, w)
+	if err != nil {
+		return 0
+	}
+	return parent
+}
+
+func (b *xBackend) SizeHints(w xproto.Window) (h sizeHints) {
+	nh, err := icccm.WmNormalHintsGet(b.X, w)
+	if err != nil {
+		return
+	}
+
+	if nh.Flags&icccm.SizeHintPMinSize > 0 {
+		h.width.min, h.height.min = int(nh.MinWidth), int(nh.MinHeight)
+	}
+
+	if nh.Flags&icccm.SizeHintPMaxSize > 0 {
+		h.width.max, h.height.max = int(nh.MaxWidth), int(nh.MaxHeight)
+	}
+
+	if nh.Flags&icccm.SizeHintPResizeInc > 0 {
+		h.width.inc, h.height.inc = int(nh.WidthInc), int(nh.HeightInc)
+	}
+
+	// The minimum size is used as base size when there is none, as per ICCCM.
+	if nh.Flags&icccm.SizeHintPBaseSize > 0 {
+		h.width.base, h.height.base = int(nh.BaseWidth), int(nh.BaseHeight)
+	} else {
+		h.width.base, h.height.base = h.width.min, h.height.min
+	}
+
+	return
+}
+
+func (b *xBackend) HasDecoration(w xproto.Window) bool {
+	mh, err := motif.WmHintsGet(b.X, w)
+	if err != nil {
+		return true
+	}
+
+	return motif.Decor(mh)
+}
+
+func (b *xBackend) MoveResize(w xproto.Window, x, y, width, height int) error {
+	return xwindow.New(b.X, w).WMMoveResize(x, y, width, height)
+}
+
+func (b *xBackend) Move(w xproto.Window, x, y int) {
+	xwindow.New(b.X, w).WMMove(x, y)
+}
+
+func (b *xBackend) Resize(w xproto.Window, width, height int) {
+	xwindow.New(b.X, w).WMResize(width, height)
+}
+
+func (b *xBackend) SetDecoration(w xproto.Window, decorated bool) {
+	decoration := uint(motif.DecorationNone)
+	if decorated {
+		decoration = motif.DecorationAll
+	}
+
+	motif.WmHintsSet(b.X, w, &motif.Hints{
+		Flags:      motif.HintDecorations,
+		Decoration: decoration,
+	})
+}
+
+func (b *xBackend) Unmaximize(w xproto.Window) {
+	ewmh.WmStateReq(b.X, w, 0, "_NET_WM_STATE_MAXIMIZED_VERT")
+	ewmh.WmStateReq(b.X, w, 0, "_NET_WM_STATE_MAXIMIZED_HORZ")
+}
+
+func (b *xBackend) Activate(w xproto.Window) {
+	ewmh.ActiveWindowReq(b.X, w)
+}
+
+func (b *xBackend) SetDesktop(w xproto.Window, desk uint) {
+	ewmh.WmDesktopReq(b.X, w, desk)
+}
+
+func (b *xBackend) Sync() {
+	b.X.Conn().Sync()
+}
+
+func (b *xBackend) Clients() []xproto.Window {
+	clients, _ := ewmh.ClientListStackingGet(b.X)
+	return clients
+}
+
+func (b *xBackend) WorkArea(desk, head uint) (x, y, width, height int) {
+	return state.WorkAreaDimensions(desk, head)
+}
+
+func (b *xBackend) ActiveHead() uint {
+	return state.ActiveHead()
+}
+
+func (b *xBackend) HeadCount() uint {
+	return state.HeadCount()
+}
+
+func (b *xBackend) HeadForRect(r xrect.Rect) uint {
+	return state.HeadForRect(r)
+}
+
+// Watch dispatches the property events of the window by atom,
+// so that no request is made for the properties that are not handled.
+// Atoms are cached by xprop, they are interned once for all windows.
+func (b *xBackend) Watch(w xproto.Window, handlers map[string]func()) {
+	byAtom := make(map[xproto.Atom]func(), len(handlers))
+	for name, f := range handlers {
+		a, err := xprop.Atm(b.X, name)
+		if err != nil {
+			log.Warn("Error interning ", name, ": ", err)
+			continue
+		}
+		byAtom[a] = f
+	}
+
+	b.listen(w, xproto.EventMaskPropertyChange)
+	xevent.PropertyNotifyFun(func(X *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
+		if f, ok := byAtom[ev.Atom]; ok {
+			f()
+		}
+	}).Connect(b.X, w)
+}
+
+func (b *xBackend) WatchGeometry(w xproto.Window, f func(x, y, width, height int)) {
+	b.listen(w, xproto.EventMaskStructureNotify)
+	xevent.ConfigureNotifyFun(func(X *xgbutil.XUtil, ev xevent.ConfigureNotifyEvent) {
+		f(int(ev.X), int(ev.Y), int(ev.Width), int(ev.Height))
+	}).Connect(b.X, w)
+}
+
+func (b *xBackend) WatchHeads(f func()) {
+	state.OnGeometryChange(f)
+}
+
+// listen adds to the events selected on the window, since selecting events replaces the previous mask.
+func (b *xBackend) listen(w xproto.Window, mask int) {
+	// The event mask of the root window is set by the state package.
+	if w == b.X.RootWin() {
+		return
+	}
+
+	b.events[w] |= mask
+	xwindow.New(b.X, w).Listen(b.events[w])
+}
+
+func (b *xBackend) Unwatch(w xproto.Window) {
+	delete(b.events, w)
+	xevent.Detach(b.X, w)
+}
+
+func (b *xBackend) Root() xproto.Window {
+	return b.X.RootWin()
+}